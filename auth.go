@@ -0,0 +1,172 @@
+package goravel
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/saalikmubeen/goravel/auth"
+	"github.com/saalikmubeen/goravel/mailer"
+	"github.com/saalikmubeen/goravel/urlsigner"
+)
+
+// createAuth sets up the auth service on top of the session. Users, remember
+// me tokens and API tokens are read from the tables created by "goravel make auth",
+// and roles and permissions from those of "goravel make rbac", so they are only
+// available when a database is configured.
+func (g *Goravel) createAuth() (*auth.Auth, error) {
+	a := &auth.Auth{
+		AppName:  g.AppName,
+		Session:  g.Session,
+		Gate:     auth.NewGate(),
+		LoginURL: os.Getenv("AUTH_LOGIN_URL"),
+		ErrorLog: g.ErrorLog,
+	}
+
+	if a.LoginURL == "" {
+		a.LoginURL = "/users/login"
+	}
+
+	// models hash passwords with auth.HashPassword, so they use the configured hasher too
+	hasher, err := g.createHasher()
+	if err != nil {
+		return nil, err
+	}
+	a.Hasher = hasher
+	auth.DefaultHasher = a.Hasher
+
+	if path := os.Getenv("HASH_PWNED_PATH"); path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(g.RootPath, path)
+		}
+		a.Pwned = &auth.PwnedPasswords{Path: path, MinCount: envInt("HASH_PWNED_MIN_COUNT", 1)}
+	}
+
+	a.Throttle = &auth.LoginThrottle{
+		Cache:         g.Cache,
+		MaxAttempts:   envInt("AUTH_MAX_ATTEMPTS", 5),
+		MaxIPAttempts: envInt("AUTH_MAX_IP_ATTEMPTS", 20),
+		LockoutFor:    envSeconds("AUTH_LOCKOUT_SECONDS", 15*time.Minute),
+		Delay:         envSeconds("AUTH_THROTTLE_DELAY_SECONDS", time.Second),
+		CaptchaAfter:  envInt("AUTH_CAPTCHA_AFTER", 0),
+		OnLockout:     g.notifyLockout,
+		ErrorLog:      g.ErrorLog,
+	}
+
+	if g.DB.Pool != nil {
+		a.Users = &auth.SQLUserProvider{DB: g.DB.Pool, DatabaseType: g.DB.DatabaseType}
+		a.RememberTokens = &auth.SQLRememberTokenStore{DB: g.DB.Pool, DatabaseType: g.DB.DatabaseType}
+		a.Tokens = &auth.SQLTokenStore{DB: g.DB.Pool, DatabaseType: g.DB.DatabaseType}
+		a.Roles = &auth.RBAC{
+			DB:           g.DB.Pool,
+			DatabaseType: g.DB.DatabaseType,
+			Cache:        g.Cache,
+			CacheFor:     envInt("AUTH_RBAC_CACHE_SECONDS", 3600),
+		}
+		a.Verification = &auth.EmailVerification{
+			Signer:      &urlsigner.Signer{Secret: []byte(g.EncryptionKey)},
+			URL:         g.Server.URL + "/users/verify-email",
+			ExpiresIn:   envInt("AUTH_VERIFICATION_EXPIRY", 60),
+			ResendEvery: envSeconds("AUTH_VERIFICATION_RESEND_SECONDS", time.Minute),
+			Store:       &auth.SQLVerificationStore{DB: g.DB.Pool, DatabaseType: g.DB.DatabaseType},
+			Cache:       g.Cache,
+			NoticeURL:   "/users/verify-email/notice",
+		}
+
+		// the 2FA tables only exist in apps that ran the migrations of a recent "goravel make auth"
+		if envBool("AUTH_TWO_FACTOR", false) {
+			a.TwoFactor = &auth.SQLTwoFactorStore{
+				DB:           g.DB.Pool,
+				DatabaseType: g.DB.DatabaseType,
+				Cipher:       &Encryption{Key: []byte(g.EncryptionKey)},
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// createHasher returns the password hasher configured by HASH_DRIVER. Hashes
+// of the other driver keep working, and are upgraded when their users log in.
+func (g *Goravel) createHasher() (auth.Hasher, error) {
+	h := &auth.Hashing{
+		Driver: os.Getenv("HASH_DRIVER"),
+		Bcrypt: auth.BcryptHasher{Cost: envInt("HASH_BCRYPT_COST", 12)},
+		Argon2id: auth.Argon2idHasher{
+			Memory:      uint32(envInt("HASH_ARGON2_MEMORY", 64*1024)),
+			Iterations:  uint32(envInt("HASH_ARGON2_ITERATIONS", 3)),
+			Parallelism: uint8(envInt("HASH_ARGON2_PARALLELISM", 2)),
+		},
+	}
+	if err := h.Validate(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// notifyLockout emails the owner of an account that got locked after too
+// many failed logins, using the account-locked mail template created by
+// "goravel make auth". Nothing is sent for emails without an account.
+func (g *Goravel) notifyLockout(email string) {
+	if g.Auth == nil || g.Auth.Users == nil {
+		return
+	}
+	if _, err := g.Auth.Users.FindByEmail(email); err != nil {
+		return
+	}
+
+	var data struct {
+		Minutes int
+	}
+	data.Minutes = int(g.Auth.Throttle.LockoutFor / time.Minute)
+
+	msg := mailer.Message{
+		To:       email,
+		Subject:  "Your account was locked",
+		Template: "account-locked",
+		Data:     data,
+	}
+
+	// sent in the background, so the failed login isn't slowed down
+	go func() {
+		if err := g.Mail.Send(msg); err != nil {
+			g.ErrorLog.Println("sending lockout notification:", err)
+		}
+	}()
+}
+
+// Can reports whether the user of the request may perform ability, e.g.
+// g.Can(r, "update", post). Abilities and policies are registered on
+// g.Auth.Gate.
+func (g *Goravel) Can(r *http.Request, ability string, args ...interface{}) bool {
+	return g.Auth.Can(r, ability, args...)
+}
+
+// Authorize checks an ability like Can, and responds with a 403 if it is
+// denied. Handlers should return when it reports false:
+//
+//	if !app.Authorize(w, r, "update", post) {
+//		return
+//	}
+func (g *Goravel) Authorize(w http.ResponseWriter, r *http.Request, ability string, args ...interface{}) bool {
+	if !g.Can(r, ability, args...) {
+		g.ErrorForbidden(w, r)
+		return false
+	}
+	return true
+}
+
+// CanMiddleware returns a middleware that only lets requests through whose
+// user may perform ability, responding with a 403 otherwise. It suits
+// abilities that don't need a model, e.g. r.With(app.CanMiddleware("view-reports")).
+func (g *Goravel) CanMiddleware(ability string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !g.Authorize(w, r, ability) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
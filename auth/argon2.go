@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrInvalidHash is returned when checking a password against a malformed hash
+var ErrInvalidHash = errors.New("auth: invalid password hash")
+
+// Argon2idHasher hashes passwords with argon2id. Hashes are stored in the
+// usual encoded form, e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>, so
+// they carry their own parameters and keep working when these change.
+type Argon2idHasher struct {
+	Memory      uint32 // KiB, 64 MiB if not set
+	Iterations  uint32 // 3 if not set
+	Parallelism uint8  // 2 if not set
+	SaltLength  uint32 // bytes, 16 if not set
+	KeyLength   uint32 // bytes, 32 if not set
+}
+
+// argon2Params are the parameters of an encoded hash
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// Hash returns the encoded argon2id hash of password, with a random salt
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.saltLength())
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := h.params()
+	key := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, h.keyLength())
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.memory, p.iterations, p.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Check reports whether password matches an encoded argon2id hash
+func (h *Argon2idHasher) Check(password, hash string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.iterations, p.memory, p.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash reports whether hash isn't an argon2id hash of the configured parameters
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return p != h.params() || uint32(len(salt)) != h.saltLength() || uint32(len(key)) != h.keyLength()
+}
+
+func (h *Argon2idHasher) params() argon2Params {
+	p := argon2Params{memory: h.Memory, iterations: h.Iterations, parallelism: h.Parallelism}
+	if p.memory == 0 {
+		p.memory = 64 * 1024
+	}
+	if p.iterations == 0 {
+		p.iterations = 3
+	}
+	if p.parallelism == 0 {
+		p.parallelism = 2
+	}
+	return p
+}
+
+func (h *Argon2idHasher) saltLength() uint32 {
+	if h.SaltLength == 0 {
+		return 16
+	}
+	return h.SaltLength
+}
+
+func (h *Argon2idHasher) keyLength() uint32 {
+	if h.KeyLength == 0 {
+		return 32
+	}
+	return h.KeyLength
+}
+
+// decodeArgon2id splits an encoded argon2id hash into its parameters, salt and key
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,358 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// SessionUserKey is the session key holding the ID of the logged in user
+const SessionUserKey = "userID"
+
+var (
+	// ErrInvalidCredentials is returned by Attempt when the email or password is wrong.
+	// It deliberately doesn't say which one.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnauthenticated is returned when a request has no logged in user
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrUserNotFound is returned by user providers when there is no matching user
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserInactive is returned when a deactivated user tries to log in or use a token
+	ErrUserInactive = errors.New("user is deactivated")
+)
+
+// User is anything that can log in
+type User interface {
+	// AuthID returns the user's unique ID
+	AuthID() int
+	// AuthPassword returns the user's password hash
+	AuthPassword() string
+}
+
+// Deactivatable is implemented by users that can be deactivated, e.g. by an
+// admin. Inactive users can't log in by any means; users that don't
+// implement it are always active.
+type Deactivatable interface {
+	IsActive() bool
+}
+
+// active reports whether user may be logged in
+func active(user User) bool {
+	d, ok := user.(Deactivatable)
+	return !ok || d.IsActive()
+}
+
+// UserProvider looks users up for the Auth service. SQLUserProvider works with
+// the users table created by "goravel make auth"; apps can plug in their own
+// to authenticate against anything else.
+type UserProvider interface {
+	FindByID(id int) (User, error)
+	FindByEmail(email string) (User, error)
+}
+
+// PasswordUpdater is implemented by user providers that can store a new
+// password hash, which lets Attempt upgrade outdated hashes
+type PasswordUpdater interface {
+	UpdatePassword(userID int, hash string) error
+}
+
+// Auth handles logging users in and out, remember me cookies and API tokens
+type Auth struct {
+	AppName        string // used to name the remember me cookie
+	Session        *scs.SessionManager
+	Users          UserProvider
+	Hasher         Hasher
+	RememberTokens RememberTokenStore
+	Tokens         TokenStore
+	TwoFactor      TwoFactorStore     // nil disables two-factor authentication
+	Gate           *Gate              // abilities checked by Can
+	Roles          *RBAC              // roles and permissions; nil without a database
+	Verification   *EmailVerification // nil disables email verification
+	Throttle       *LoginThrottle     // limits failed logins in AttemptRequest; nil disables it
+	Pwned          *PwnedPasswords    // list of breached passwords; nil disables the check
+	RememberFor    time.Duration      // lifetime of the remember me cookie, a year if not set
+	LoginURL       string             // where RequireUser sends guests; they get a 401 if empty
+	ErrorLog       *log.Logger        // errors that don't fail a request, e.g. a failed last used update; dropped if nil
+
+	timingOnce sync.Once
+	timing     string
+}
+
+// Attempt checks an email and password, returning the user they belong to
+func (a *Auth) Attempt(email, password string) (User, error) {
+	user, err := a.Users.FindByEmail(email)
+	if errors.Is(err, ErrUserNotFound) {
+		// hash anyway, so the response time doesn't tell whether the email exists
+		_, _ = a.hasher().Check(password, a.timingHash())
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := a.hasher().Check(password, user.AuthPassword())
+	if err != nil {
+		return nil, err
+	}
+	if !matches {
+		return nil, ErrInvalidCredentials
+	}
+	if !active(user) {
+		return nil, ErrUserInactive
+	}
+
+	a.rehash(user, password)
+	return user, nil
+}
+
+// rehash replaces the stored hash of a user who just logged in, if it was
+// made by another hasher or with weaker settings than the current ones. The
+// login succeeds even if this fails; the next one tries again.
+func (a *Auth) rehash(user User, password string) {
+	updater, ok := a.Users.(PasswordUpdater)
+	if !ok || !a.hasher().NeedsRehash(user.AuthPassword()) {
+		return
+	}
+
+	hash, err := a.hasher().Hash(password)
+	if err != nil {
+		return
+	}
+	_ = updater.UpdatePassword(user.AuthID(), hash)
+}
+
+// Login logs user in for the current session. The session gets a new token
+// to prevent session fixation. With remember set, a remember me cookie keeps
+// the user logged in after the session has expired.
+//
+// If the user has two-factor authentication enabled, they aren't logged in
+// yet: Login returns ErrTwoFactorRequired and the login is finished by
+// CompleteTwoFactor once they enter a code.
+func (a *Auth) Login(w http.ResponseWriter, r *http.Request, user User, remember bool) error {
+	if !active(user) {
+		return ErrUserInactive
+	}
+
+	enabled, err := a.TwoFactorEnabled(user.AuthID())
+	if err != nil {
+		return err
+	}
+	if enabled {
+		if err := a.startTwoFactor(r, user, remember); err != nil {
+			return err
+		}
+		return ErrTwoFactorRequired
+	}
+
+	return a.login(w, r, user, remember)
+}
+
+func (a *Auth) login(w http.ResponseWriter, r *http.Request, user User, remember bool) error {
+	ctx := r.Context()
+
+	// also checked here, as CompleteTwoFactor logs in a user looked up afresh
+	if !active(user) {
+		return ErrUserInactive
+	}
+
+	err := a.Session.RenewToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if remember && a.RememberTokens != nil {
+		err = a.remember(w, r, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.Session.Put(ctx, SessionUserKey, user.AuthID())
+	setRequestUser(ctx, user, nil)
+	return nil
+}
+
+// Logout logs the current user out, deleting their remember me token and
+// cookie and destroying the session
+func (a *Auth) Logout(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	if token := a.Session.GetString(ctx, sessionRememberKey); token != "" && a.RememberTokens != nil {
+		err := a.RememberTokens.Delete(hashToken(token))
+		if err != nil {
+			return err
+		}
+	}
+	a.forgetCookie(w)
+	setRequestUser(ctx, nil, ErrUnauthenticated)
+
+	return a.Session.Destroy(ctx)
+}
+
+// Check reports whether the request has a logged in user who is still
+// active. Behind CacheUser the user is only looked up once per request.
+func (a *Auth) Check(r *http.Request) bool {
+	if _, ok := UserFromContext(r.Context()); ok {
+		return true
+	}
+	if a.Users == nil {
+		return a.Session.Exists(r.Context(), SessionUserKey)
+	}
+	_, err := a.User(r)
+	return err == nil
+}
+
+// ID returns the ID of the logged in user, or 0 if there is none
+func (a *Auth) ID(r *http.Request) int {
+	if user, ok := UserFromContext(r.Context()); ok {
+		return user.AuthID()
+	}
+	return a.Session.GetInt(r.Context(), SessionUserKey)
+}
+
+// User returns the logged in user. Behind CacheUser the user is looked up
+// once per request and reused by later calls.
+func (a *Auth) User(r *http.Request) (User, error) {
+	if user, ok := UserFromContext(r.Context()); ok {
+		return user, nil
+	}
+
+	memo, ok := r.Context().Value(userMemoContextKey).(*userMemo)
+	if !ok {
+		return a.sessionUser(r)
+	}
+
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	if !memo.resolved {
+		memo.user, memo.err = a.sessionUser(r)
+		memo.resolved = true
+	}
+	return memo.user, memo.err
+}
+
+// CacheUser is a middleware that keeps the user of a request once it has
+// been looked up, so checks like Can, which may run many times while a page
+// renders, query the database at most once. goravel's router adds it after
+// the session middleware.
+func (a *Auth) CacheUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), userMemoContextKey, &userMemo{})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// userMemo holds the user of a request for CacheUser; logging in or out
+// during the request replaces it
+type userMemo struct {
+	mu       sync.Mutex
+	resolved bool
+	user     User
+	err      error
+}
+
+// setRequestUser replaces the user kept by CacheUser, if there is one
+func setRequestUser(ctx context.Context, user User, err error) {
+	memo, ok := ctx.Value(userMemoContextKey).(*userMemo)
+	if !ok {
+		return
+	}
+
+	memo.mu.Lock()
+	defer memo.mu.Unlock()
+	memo.user, memo.err, memo.resolved = user, err, true
+}
+
+// sessionUser looks up the user whose ID is in the session
+func (a *Auth) sessionUser(r *http.Request) (User, error) {
+	if !a.Session.Exists(r.Context(), SessionUserKey) {
+		return nil, ErrUnauthenticated
+	}
+
+	user, err := a.Users.FindByID(a.Session.GetInt(r.Context(), SessionUserKey))
+	if err != nil {
+		return nil, err
+	}
+	if !active(user) {
+		return nil, ErrUserInactive
+	}
+	return user, nil
+}
+
+// Can reports whether the user of the request (nil for guests) may perform
+// ability, as decided by the Gate
+func (a *Auth) Can(r *http.Request, ability string, args ...interface{}) bool {
+	if a.Gate == nil {
+		return false
+	}
+
+	user, ok := UserFromContext(r.Context())
+	if !ok && a.Users != nil {
+		user, _ = a.User(r)
+	}
+	return a.Gate.Allows(user, ability, args...)
+}
+
+// logError logs an error that doesn't fail the request it happened in
+func (a *Auth) logError(message string, err error) {
+	if a.ErrorLog != nil {
+		a.ErrorLog.Println(message, err)
+	}
+}
+
+func (a *Auth) hasher() Hasher {
+	if a.Hasher == nil {
+		return DefaultHasher
+	}
+	return a.Hasher
+}
+
+// timingHash returns a hash made by the current hasher, to check passwords
+// of unknown users against at the same cost as real ones
+func (a *Auth) timingHash() string {
+	a.timingOnce.Do(func() {
+		hash, err := a.hasher().Hash("not a real password")
+		if err != nil {
+			hash = dummyHash
+		}
+		a.timing = hash
+	})
+	return a.timing
+}
+
+type contextKey string
+
+const (
+	userContextKey     contextKey = "auth.user"
+	tokenContextKey    contextKey = "auth.token"
+	userMemoContextKey contextKey = "auth.userMemo"
+)
+
+// WithUser returns a copy of ctx carrying an authenticated user
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the user put in the request context by the token
+// middleware, if there is one
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userContextKey).(User)
+	return user, ok
+}
+
+// WithToken returns a copy of ctx carrying the token a request was authenticated with
+func WithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenContextKey, token)
+}
+
+// TokenFromContext returns the token put in the request context by the token
+// middleware, if there is one
+func TokenFromContext(ctx context.Context) (*Token, bool) {
+	token, ok := ctx.Value(tokenContextKey).(*Token)
+	return token, ok
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrForbidden is returned by Gate.Authorize when an ability is denied
+var ErrForbidden = errors.New("forbidden")
+
+// AbilityFunc decides whether user may do something. user is nil for guests;
+// args are whatever was passed to the check, usually the model being acted on.
+type AbilityFunc func(user User, args ...interface{}) bool
+
+// BeforeFunc runs before every check. Returning decided true skips the
+// ability and uses allowed instead, e.g. to let admins do everything.
+type BeforeFunc func(user User, ability string) (allowed, decided bool)
+
+// Gate holds the abilities of an app. Abilities are either closures
+// registered with Define, or methods of policy structs registered with
+// Policy, e.g. a PostPolicy with
+//
+//	func (p *PostPolicy) Update(user auth.User, post *models.Post) bool
+//
+// which is used for Allows(user, "update", post).
+//
+// The user passed to abilities and policies is whatever Auth.Users returns,
+// a *DefaultUser with goravel's SQLUserProvider, so they should take
+// auth.User. A policy method may take the app's own user type instead when
+// Auth.Users returns that type; for a logged in user of another type the
+// method isn't called and the ability is denied.
+type Gate struct {
+	mu        sync.RWMutex
+	abilities map[string]AbilityFunc
+	policies  map[reflect.Type]reflect.Value
+	before    []BeforeFunc
+}
+
+// NewGate returns an empty gate
+func NewGate() *Gate {
+	return &Gate{
+		abilities: make(map[string]AbilityFunc),
+		policies:  make(map[reflect.Type]reflect.Value),
+	}
+}
+
+// Define registers an ability
+func (g *Gate) Define(ability string, fn AbilityFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.abilities[ability] = fn
+}
+
+// Policy registers policy as the place to look up abilities for a model
+// type. model is any value of the type, e.g. &models.Post{}; checks match
+// both the pointer and the value type.
+func (g *Gate) Policy(model, policy interface{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.policies[indirectType(reflect.TypeOf(model))] = reflect.ValueOf(policy)
+}
+
+// Before registers a check that runs before every ability
+func (g *Gate) Before(fn BeforeFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.before = append(g.before, fn)
+}
+
+// Allows reports whether user may perform ability. When the first argument
+// is a model with a registered policy, the policy decides; otherwise the
+// ability defined with Define does. Unknown abilities are denied.
+func (g *Gate) Allows(user User, ability string, args ...interface{}) bool {
+	g.mu.RLock()
+	before := g.before
+	fn, defined := g.abilities[ability]
+	g.mu.RUnlock()
+
+	for _, b := range before {
+		if allowed, decided := b(user, ability); decided {
+			return allowed
+		}
+	}
+
+	if len(args) > 0 && args[0] != nil {
+		if allowed, found := g.checkPolicy(user, ability, args); found {
+			return allowed
+		}
+	}
+
+	if !defined {
+		return false
+	}
+	return fn(user, args...)
+}
+
+// Denies is the opposite of Allows
+func (g *Gate) Denies(user User, ability string, args ...interface{}) bool {
+	return !g.Allows(user, ability, args...)
+}
+
+// Authorize returns ErrForbidden if user may not perform ability
+func (g *Gate) Authorize(user User, ability string, args ...interface{}) error {
+	if !g.Allows(user, ability, args...) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// checkPolicy calls the policy method for ability, if the model has a policy with one
+func (g *Gate) checkPolicy(user User, ability string, args []interface{}) (allowed, found bool) {
+	g.mu.RLock()
+	policy, ok := g.policies[indirectType(reflect.TypeOf(args[0]))]
+	g.mu.RUnlock()
+	if !ok {
+		return false, false
+	}
+
+	method := policy.MethodByName(methodName(ability))
+	if !method.IsValid() {
+		return false, false
+	}
+
+	mt := method.Type()
+	if mt.NumOut() != 1 || mt.Out(0).Kind() != reflect.Bool || mt.NumIn() != len(args)+1 {
+		return false, false
+	}
+
+	u, ok := userValue(user, mt.In(0))
+	if !ok {
+		return false, true
+	}
+
+	in := make([]reflect.Value, 0, len(args)+1)
+	in = append(in, u)
+	for i, arg := range args {
+		v, ok := convertArg(arg, mt.In(i+1))
+		if !ok {
+			return false, false
+		}
+		in = append(in, v)
+	}
+
+	return method.Call(in)[0].Bool(), true
+}
+
+// userValue passes user as the policy method's first parameter, which may be
+// auth.User or the app's concrete user type. A guest becomes the zero value
+// (nil for pointers and interfaces). A user of another type reports false:
+// passing nil would let the policy mistake them for a guest.
+func userValue(user User, t reflect.Type) (reflect.Value, bool) {
+	if user == nil {
+		return reflect.Zero(t), true
+	}
+
+	v := reflect.ValueOf(user)
+	if !v.Type().AssignableTo(t) {
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+// convertArg adapts a check argument to a policy parameter, dereferencing
+// a pointer if the policy takes the value
+func convertArg(arg interface{}, t reflect.Type) (reflect.Value, bool) {
+	if arg == nil {
+		return reflect.Zero(t), true
+	}
+
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(t) {
+		return v, true
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Type().AssignableTo(t) {
+		return v.Elem(), true
+	}
+	return reflect.Value{}, false
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// methodName turns an ability into a policy method name: "update" becomes
+// Update, "view-any" and "view_any" become ViewAny
+func methodName(ability string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range ability {
+		if r == '-' || r == '_' || r == ' ' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
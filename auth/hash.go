@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher hashes passwords and checks them against stored hashes
+type Hasher interface {
+	Hash(password string) (string, error)
+	// Check reports whether password matches hash. A wrong password is not an error.
+	Check(password, hash string) (bool, error)
+	// NeedsRehash reports whether hash was made with other settings than the
+	// hasher's current ones, so it should be replaced by a fresh hash
+	NeedsRehash(hash string) bool
+}
+
+// ErrUnknownHashDriver is returned by Hashing.Validate for unsupported drivers
+var ErrUnknownHashDriver = errors.New("auth: unknown hash driver")
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int // bcrypt.DefaultCost if not set
+}
+
+// DefaultHasher is used when Auth has no Hasher of its own. goravel replaces
+// it with the hasher configured by HASH_DRIVER when the app starts.
+var DefaultHasher Hasher = &Hashing{Driver: "bcrypt", Bcrypt: BcryptHasher{Cost: 12}}
+
+// dummyHash is checked against when a user doesn't exist and the hasher
+// can't make a hash of its own, so failed logins take the same time whether
+// or not the email is known
+var dummyHash = "$2a$12$C6UzMDM.H6dfI/f/IKcEeO5N2LOyBmQ1i/i1vUu8gFxn0eRfEGyLy"
+
+// Hash returns the bcrypt hash of password
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Check reports whether password matches a bcrypt hash
+func (h *BcryptHasher) Check(password, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// NeedsRehash reports whether hash isn't a bcrypt hash of the configured cost
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost()
+}
+
+func (h *BcryptHasher) cost() int {
+	if h.Cost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return h.Cost
+}
+
+// Hashing hashes new passwords with the hasher of Driver, and checks
+// passwords against hashes of either built-in hasher, so switching drivers
+// doesn't lock out existing users. Their old hashes need a rehash, which
+// Auth.Attempt does when they log in.
+type Hashing struct {
+	Driver   string // "bcrypt" (the default) or "argon2id", in any case
+	Bcrypt   BcryptHasher
+	Argon2id Argon2idHasher
+}
+
+// Hash hashes password with the configured driver
+func (h *Hashing) Hash(password string) (string, error) {
+	return h.driver().Hash(password)
+}
+
+// Check reports whether password matches hash, whichever driver made it
+func (h *Hashing) Check(password, hash string) (bool, error) {
+	if isArgon2idHash(hash) {
+		return h.Argon2id.Check(password, hash)
+	}
+	return h.Bcrypt.Check(password, hash)
+}
+
+// NeedsRehash reports whether hash was made by another driver, or by the
+// configured one with other settings
+func (h *Hashing) NeedsRehash(hash string) bool {
+	if isArgon2idHash(hash) != (h.driverName() == "argon2id") {
+		return true
+	}
+	return h.driver().NeedsRehash(hash)
+}
+
+// Validate returns ErrUnknownHashDriver for a Driver other than bcrypt and
+// argon2id, which would otherwise silently fall back to bcrypt
+func (h *Hashing) Validate() error {
+	switch h.driverName() {
+	case "", "bcrypt", "argon2id":
+		return nil
+	}
+	return fmt.Errorf("%w: %q, use bcrypt or argon2id", ErrUnknownHashDriver, h.Driver)
+}
+
+func (h *Hashing) driver() Hasher {
+	if h.driverName() == "argon2id" {
+		return &h.Argon2id
+	}
+	return &h.Bcrypt
+}
+
+func (h *Hashing) driverName() string {
+	return strings.ToLower(strings.TrimSpace(h.Driver))
+}
+
+func isArgon2idHash(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+// HashPassword hashes a password with the DefaultHasher
+func HashPassword(password string) (string, error) {
+	return DefaultHasher.Hash(password)
+}
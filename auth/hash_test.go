@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+// small parameters keep the tests fast
+func testArgon2id() Argon2idHasher {
+	return Argon2idHasher{Memory: 1024, Iterations: 1, Parallelism: 1}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	h := testArgon2id()
+	hash, err := h.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := h.Check("secret", hash); !ok || err != nil {
+		t.Fatalf("Check(right password) = %v, %v", ok, err)
+	}
+	if ok, err := h.Check("wrong", hash); ok || err != nil {
+		t.Fatalf("Check(wrong password) = %v, %v", ok, err)
+	}
+	if h.NeedsRehash(hash) {
+		t.Fatal("a fresh hash needs a rehash")
+	}
+
+	for name, changed := range map[string]Argon2idHasher{
+		"memory":      {Memory: 2048, Iterations: 1, Parallelism: 1},
+		"iterations":  {Memory: 1024, Iterations: 2, Parallelism: 1},
+		"parallelism": {Memory: 1024, Iterations: 1, Parallelism: 2},
+		"salt length": {Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 32},
+		"key length":  {Memory: 1024, Iterations: 1, Parallelism: 1, KeyLength: 64},
+	} {
+		if !changed.NeedsRehash(hash) {
+			t.Errorf("no rehash after changing the %s", name)
+		}
+	}
+
+	if !h.NeedsRehash("$argon2id$v=19$m=1024,t=1,p=1$bad") {
+		t.Error("a malformed hash doesn't need a rehash")
+	}
+}
+
+func TestHashingSwitchesDrivers(t *testing.T) {
+	bcryptHashing := &Hashing{Driver: "bcrypt", Bcrypt: BcryptHasher{Cost: 4}, Argon2id: testArgon2id()}
+	argonHashing := &Hashing{Driver: " Argon2ID ", Bcrypt: BcryptHasher{Cost: 4}, Argon2id: testArgon2id()}
+
+	old, err := bcryptHashing.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// users hashed with the old driver can still log in, and get rehashed
+	if ok, err := argonHashing.Check("secret", old); !ok || err != nil {
+		t.Fatalf("Check(bcrypt hash) = %v, %v", ok, err)
+	}
+	if !argonHashing.NeedsRehash(old) {
+		t.Fatal("a bcrypt hash doesn't need a rehash after switching to argon2id")
+	}
+
+	hash, err := argonHashing.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isArgon2idHash(hash) || argonHashing.NeedsRehash(hash) {
+		t.Fatalf("Hash with driver %q made %q", argonHashing.Driver, hash)
+	}
+	if !bcryptHashing.NeedsRehash(hash) {
+		t.Fatal("an argon2id hash doesn't need a rehash after switching to bcrypt")
+	}
+}
+
+func TestHashingValidate(t *testing.T) {
+	for driver, valid := range map[string]bool{
+		"":          true,
+		"bcrypt":    true,
+		"BCRYPT":    true,
+		"argon2id ": true,
+		"argon2":    false,
+		"scrypt":    false,
+	} {
+		err := (&Hashing{Driver: driver}).Validate()
+		if valid && err != nil {
+			t.Errorf("Validate(%q) = %v", driver, err)
+		}
+		if !valid && !errors.Is(err, ErrUnknownHashDriver) {
+			t.Errorf("Validate(%q) = %v, want ErrUnknownHashDriver", driver, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// RequireUser is a middleware that only lets logged in, active users through.
+// Guests are redirected to LoginURL, or get a 401 if it isn't set. A user who
+// was deactivated or deleted since logging in is logged out and handled like
+// a guest.
+func (a *Auth) RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Users == nil {
+			// without a user provider, the session is all there is to check
+			if !a.Session.Exists(r.Context(), SessionUserKey) {
+				a.rejectGuest(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, err := a.User(r)
+		switch {
+		case err == nil:
+			next.ServeHTTP(w, r)
+		case errors.Is(err, ErrUserInactive), errors.Is(err, ErrUserNotFound):
+			if err := a.Logout(w, r); err != nil {
+				a.logError("could not log out a deactivated user", err)
+			}
+			a.rejectGuest(w, r)
+		case errors.Is(err, ErrUnauthenticated):
+			a.rejectGuest(w, r)
+		default:
+			a.logError("could not load the logged in user", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+// rejectGuest redirects a guest to LoginURL, or answers with a 401
+func (a *Auth) rejectGuest(w http.ResponseWriter, r *http.Request) {
+	if a.LoginURL != "" {
+		http.Redirect(w, r, a.LoginURL, http.StatusSeeOther)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// RequireToken is a middleware that only lets requests with a valid bearer
+// token through. The token and its user are available from TokenFromContext
+// and UserFromContext.
+func (a *Auth) RequireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, token, err := a.AuthenticateToken(r)
+		if err != nil {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid authentication credentials")
+			return
+		}
+
+		ctx := WithToken(WithUser(r.Context(), user), token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAbilities returns a middleware that only lets requests through whose
+// bearer token has all of the given abilities. It authenticates the token
+// itself when RequireToken hasn't already done so, e.g.
+//
+//	r.With(app.Auth.RequireAbilities("posts:write")).Post("/posts", h.CreatePost)
+func (a *Auth) RequireAbilities(abilities ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		check := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _ := TokenFromContext(r.Context())
+			for _, ability := range abilities {
+				if !token.Can(ability) {
+					writeJSONError(w, http.StatusForbidden, ErrMissingAbility.Error()+": "+ability)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := TokenFromContext(r.Context()); ok {
+				check.ServeHTTP(w, r)
+				return
+			}
+			a.RequireToken(check).ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeJSONError sends an error in the same shape as goravel's JSON responses
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   true,
+		"message": message,
+	})
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// memoryUsers finds users in a map
+type memoryUsers map[int]*DefaultUser
+
+func (u memoryUsers) FindByID(id int) (User, error) {
+	user, ok := u[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (u memoryUsers) FindByEmail(email string) (User, error) {
+	for _, user := range u {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func TestRequireUser(t *testing.T) {
+	users := memoryUsers{1: {ID: 1, Email: "user@example.com", Active: 1}}
+	a := &Auth{Session: scs.New(), Users: users, LoginURL: "/users/login"}
+
+	var cookies []*http.Cookie
+	request := func(h http.Handler) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/dashboard", nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		a.Session.LoadAndSave(a.CacheUser(h)).ServeHTTP(w, r)
+		if c := w.Result().Cookies(); len(c) > 0 {
+			cookies = c
+		}
+		return w
+	}
+
+	dashboard := a.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Check(r) {
+			t.Error("Check is false behind RequireUser")
+		}
+		w.Write([]byte("dashboard"))
+	}))
+
+	if w := request(dashboard); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/users/login" {
+		t.Fatalf("guest got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	request(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Login(w, r, users[1], false); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	if w := request(dashboard); w.Code != http.StatusOK || w.Body.String() != "dashboard" {
+		t.Fatalf("logged in user got %d %q", w.Code, w.Body.String())
+	}
+
+	// an admin deactivates the user while they are logged in
+	users[1].Active = 0
+	if w := request(dashboard); w.Code != http.StatusSeeOther {
+		t.Fatalf("deactivated user got %d", w.Code)
+	}
+
+	// and they stay logged out when reactivated
+	users[1].Active = 1
+	if w := request(dashboard); w.Code != http.StatusSeeOther {
+		t.Fatalf("the session of a deactivated user survived, got %d", w.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PwnedPasswords checks passwords against a local copy of a list of breached
+// passwords, such as the one from Have I Been Pwned. Passwords are looked up
+// by their SHA-1 hash, so the list never holds plain passwords.
+//
+// Path is either
+//   - a directory in the layout of the k-anonymity range API: one file per 5
+//     character hash prefix (e.g. 21BD1) listing the remaining 35 characters
+//     of each hash in the range as SUFFIX:COUNT lines. Only the password's
+//     range is read, so the list can be far larger than memory.
+//   - a bloom filter file made by BuildPwnedBloom, a fraction of the size of
+//     the list. It may wrongly report a password as pwned, at the rate it
+//     was built for, and MinCount is applied when building it.
+//   - a single file with one full hash per line, optionally followed by
+//     :COUNT, for shorter lists. It is loaded into memory on first use.
+type PwnedPasswords struct {
+	Path     string
+	MinCount int // how often a password must have been seen to count, 1 if not set
+
+	once   sync.Once
+	bloom  *pwnedBloom
+	hashes map[string]int
+	err    error
+}
+
+// IsPwned reports whether password is on the list
+func (p *PwnedPasswords) IsPwned(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	if info.IsDir() {
+		count, err = p.countInRange(hash)
+	} else {
+		count, err = p.countInList(hash)
+	}
+	if err != nil {
+		return false, err
+	}
+	return count >= p.minCount(), nil
+}
+
+// countInRange reads the range file of hash's prefix
+func (p *PwnedPasswords) countInRange(hash string) (int, error) {
+	f, err := os.Open(filepath.Join(p.Path, hash[:5]))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	suffix := hash[5:]
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if s, count := parsePwnedLine(scanner.Text()); strings.EqualFold(s, suffix) {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// countInList looks hash up in the bloom filter or single file list, opening
+// or loading it the first time
+func (p *PwnedPasswords) countInList(hash string) (int, error) {
+	p.once.Do(func() {
+		bloom, ok, err := openPwnedBloom(p.Path)
+		if ok || err != nil {
+			p.bloom, p.err = bloom, err
+			return
+		}
+
+		f, err := os.Open(p.Path)
+		if err != nil {
+			p.err = err
+			return
+		}
+		defer f.Close()
+
+		p.hashes = make(map[string]int)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if h, count := parsePwnedLine(scanner.Text()); h != "" {
+				p.hashes[strings.ToUpper(h)] = count
+			}
+		}
+		p.err = scanner.Err()
+	})
+
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	if p.bloom != nil {
+		found, err := p.bloom.contains(hash)
+		if err != nil || !found {
+			return 0, err
+		}
+		return p.minCount(), nil
+	}
+	return p.hashes[hash], nil
+}
+
+func (p *PwnedPasswords) minCount() int {
+	if p.MinCount <= 0 {
+		return 1
+	}
+	return p.MinCount
+}
+
+// parsePwnedLine splits a HASH:COUNT line. A missing or invalid count counts as 1.
+func parsePwnedLine(line string) (string, int) {
+	line = strings.TrimSpace(line)
+	hash, countText, found := strings.Cut(line, ":")
+	if !found {
+		return hash, 1
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(countText))
+	if err != nil || count < 1 {
+		return hash, 1
+	}
+	return hash, count
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// pwnedBloomMagic starts a bloom filter file made by BuildPwnedBloom
+var pwnedBloomMagic = []byte("PWNBLOOM")
+
+// pwnedBloomHeader is the magic, the number of bit positions per hash (uint32)
+// and the number of bits (uint64), both big endian
+const pwnedBloomHeader = 8 + 4 + 8
+
+// pwnedBloom is a bloom filter of SHA-1 hashes, read from its file bit by
+// bit, so filters of the full Have I Been Pwned list (about 1 GB at a 1% false
+// positive rate) don't have to fit in memory
+type pwnedBloom struct {
+	f *os.File
+	k uint32
+	m uint64
+}
+
+// openPwnedBloom opens path as a bloom filter, reporting false if it isn't one
+func openPwnedBloom(path string) (*pwnedBloom, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	header := make([]byte, pwnedBloomHeader)
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.Equal(header[:8], pwnedBloomMagic) {
+		f.Close()
+		return nil, false, nil
+	}
+
+	b := &pwnedBloom{
+		f: f,
+		k: binary.BigEndian.Uint32(header[8:12]),
+		m: binary.BigEndian.Uint64(header[12:20]),
+	}
+	if b.k == 0 || b.m == 0 {
+		f.Close()
+		return nil, false, errors.New("auth: malformed pwned bloom filter")
+	}
+	return b, true, nil
+}
+
+// contains reports whether hash, an upper case hex SHA-1, may be in the filter
+func (b *pwnedBloom) contains(hash string) (bool, error) {
+	h1, h2, err := bloomHashes(hash)
+	if err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 1)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		if _, err := b.f.ReadAt(buf, pwnedBloomHeader+int64(bit/8)); err != nil {
+			return false, err
+		}
+		if buf[0]&(1<<(bit%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// bloomHashes derives the two hashes of double hashing from a SHA-1, which
+// is spread evenly enough to be used as is
+func bloomHashes(hash string) (uint64, uint64, error) {
+	sum, err := hex.DecodeString(hash)
+	if err != nil || len(sum) != 20 {
+		return 0, 0, fmt.Errorf("auth: invalid SHA-1 hash %q", hash)
+	}
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]) | 1, nil
+}
+
+// BuildPwnedBloom writes a bloom filter of the hashes in a list file, in the
+// HASH:COUNT format of Have I Been Pwned's ordered by hash download, to
+// bloomPath. Hashes seen less than minCount times are left out; the filter
+// is sized for falsePositive, e.g. 0.01 wrongly rejects one in a hundred
+// passwords that aren't on the list. Point HASH_PWNED_PATH at the result.
+func BuildPwnedBloom(listPath, bloomPath string, minCount int, falsePositive float64) error {
+	if falsePositive <= 0 || falsePositive >= 1 {
+		return errors.New("auth: the false positive rate must be between 0 and 1")
+	}
+
+	// the first pass counts the hashes, to size the filter
+	n := 0
+	err := eachPwnedHash(listPath, minCount, func(string) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		n = 1
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositive) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) / 8 * 8
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+
+	bits := make([]byte, m/8)
+	err = eachPwnedHash(listPath, minCount, func(hash string) error {
+		h1, h2, err := bloomHashes(hash)
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < uint64(k); i++ {
+			bit := (h1 + i*h2) % m
+			bits[bit/8] |= 1 << (bit % 8)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, pwnedBloomHeader)
+	copy(header, pwnedBloomMagic)
+	binary.BigEndian.PutUint32(header[8:12], k)
+	binary.BigEndian.PutUint64(header[12:20], m)
+
+	f, err := os.Create(bloomPath)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(header, bits...)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// eachPwnedHash calls fn with every upper case hash of a list file seen at
+// least minCount times
+func eachPwnedHash(path string, minCount int, fn func(hash string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, count := parsePwnedLine(scanner.Text())
+		if hash == "" || count < minCount {
+			continue
+		}
+		if err := fn(strings.ToUpper(hash)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
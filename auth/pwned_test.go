@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func pwnedHash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestPwnedPasswordsBloom(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "pwned.txt")
+	bloom := filepath.Join(dir, "pwned.bloom")
+
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, fmt.Sprintf("%s:%d", pwnedHash(fmt.Sprintf("password%d", i)), 1+i%10))
+	}
+	if err := os.WriteFile(list, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// hashes seen less than 5 times are left out
+	if err := BuildPwnedBloom(list, bloom, 5, 0.001); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PwnedPasswords{Path: bloom}
+	for i := 0; i < 1000; i++ {
+		pwned, err := p.IsPwned(fmt.Sprintf("password%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if 1+i%10 >= 5 && !pwned {
+			t.Fatalf("password%d is in the filter but not reported as pwned", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		pwned, err := p.IsPwned(fmt.Sprintf("unlisted%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pwned {
+			falsePositives++
+		}
+	}
+	if falsePositives > 10 {
+		t.Fatalf("%d of 1000 unlisted passwords reported as pwned", falsePositives)
+	}
+}
+
+func TestPwnedPasswordsList(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "pwned.txt")
+	content := pwnedHash("password") + ":3\n" + strings.ToLower(pwnedHash("letmein")) + "\n"
+	if err := os.WriteFile(list, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PwnedPasswords{Path: list, MinCount: 2}
+	for password, want := range map[string]bool{"password": true, "letmein": false, "correct horse": false} {
+		if pwned, err := p.IsPwned(password); err != nil || pwned != want {
+			t.Errorf("IsPwned(%q) = %v, %v, want %v", password, pwned, err, want)
+		}
+	}
+}
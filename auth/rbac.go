@@ -0,0 +1,421 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+var (
+	// ErrUnknownRole is returned when assigning a role that doesn't exist
+	ErrUnknownRole = errors.New("auth: unknown role")
+	// ErrUnknownPermission is returned when giving a role a permission that doesn't exist
+	ErrUnknownPermission = errors.New("auth: unknown permission")
+)
+
+// rbacCachePrefix starts the cache keys of users' roles and permissions
+const rbacCachePrefix = "rbac:"
+
+// execer runs a statement on the database or inside a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// RBAC manages roles and permissions, kept in the roles, permissions,
+// role_user and permission_role tables created by "goravel make rbac".
+// Users have roles, and roles have permissions; a user has the permissions
+// of all of their roles.
+//
+// The roles and permissions of a user are cached, so checks on every request
+// don't hit the database. Cache keys carry a version per user and one for
+// everybody, which are changed whenever an assignment changes: a list read
+// from the database just before a change is stored under the old version,
+// where nobody looks for it anymore.
+type RBAC struct {
+	DB           *sql.DB
+	DatabaseType string
+	Cache        cache.Cache // nil disables caching
+	CacheFor     int         // seconds to cache a user's roles and permissions, an hour if not set
+}
+
+// CreateRole adds a role. Creating a role that exists is not an error.
+func (b *RBAC) CreateRole(name string) error {
+	return b.insertIgnore(b.DB, "roles", "name", name)
+}
+
+// DeleteRole removes a role, taking it away from every user
+func (b *RBAC) DeleteRole(name string) error {
+	if _, err := b.DB.Exec(rebind(b.DatabaseType, "DELETE FROM roles WHERE name = ?"), name); err != nil {
+		return err
+	}
+	return b.forgetAll()
+}
+
+// CreatePermission adds a permission. Creating a permission that exists is not an error.
+func (b *RBAC) CreatePermission(name string) error {
+	return b.insertIgnore(b.DB, "permissions", "name", name)
+}
+
+// DeletePermission removes a permission, taking it away from every role
+func (b *RBAC) DeletePermission(name string) error {
+	if _, err := b.DB.Exec(rebind(b.DatabaseType, "DELETE FROM permissions WHERE name = ?"), name); err != nil {
+		return err
+	}
+	return b.forgetAll()
+}
+
+// AssignRole gives roles to a user
+func (b *RBAC) AssignRole(userID int, roles ...string) error {
+	ids, err := b.ids("roles", roles, ErrUnknownRole)
+	if err != nil {
+		return err
+	}
+
+	if err := b.assign(b.DB, userID, ids); err != nil {
+		return err
+	}
+	return b.forget(userID)
+}
+
+// assign adds role_user rows for role IDs
+func (b *RBAC) assign(db execer, userID int, roleIDs []int) error {
+	for _, id := range roleIDs {
+		if err := b.insertIgnore(db, "role_user", "user_id, role_id", userID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveRole takes roles away from a user
+func (b *RBAC) RemoveRole(userID int, roles ...string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM role_user WHERE user_id = ? AND role_id IN (SELECT id FROM roles WHERE name IN (%s))",
+		placeholders(len(roles)))
+	if _, err := b.DB.Exec(rebind(b.DatabaseType, query), append([]interface{}{userID}, names(roles)...)...); err != nil {
+		return err
+	}
+	return b.forget(userID)
+}
+
+// SyncRoles replaces all roles of a user with roles, in one transaction so
+// the user is never seen without roles in between
+func (b *RBAC) SyncRoles(userID int, roles ...string) error {
+	ids, err := b.ids("roles", roles, ErrUnknownRole)
+	if err != nil {
+		return err
+	}
+
+	tx, err := b.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(rebind(b.DatabaseType, "DELETE FROM role_user WHERE user_id = ?"), userID); err != nil {
+		return err
+	}
+	if err := b.assign(tx, userID, ids); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return b.forget(userID)
+}
+
+// GivePermission gives permissions to a role, and so to every user with the role
+func (b *RBAC) GivePermission(role string, permissions ...string) error {
+	roleIDs, err := b.ids("roles", []string{role}, ErrUnknownRole)
+	if err != nil {
+		return err
+	}
+	ids, err := b.ids("permissions", permissions, ErrUnknownPermission)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := b.insertIgnore(b.DB, "permission_role", "permission_id, role_id", id, roleIDs[0]); err != nil {
+			return err
+		}
+	}
+	return b.forgetAll()
+}
+
+// RevokePermission takes permissions away from a role
+func (b *RBAC) RevokePermission(role string, permissions ...string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`DELETE FROM permission_role
+		WHERE role_id IN (SELECT id FROM roles WHERE name = ?)
+		AND permission_id IN (SELECT id FROM permissions WHERE name IN (%s))`, placeholders(len(permissions)))
+	if _, err := b.DB.Exec(rebind(b.DatabaseType, query), append([]interface{}{role}, names(permissions)...)...); err != nil {
+		return err
+	}
+	return b.forgetAll()
+}
+
+// Roles returns the names of a user's roles
+func (b *RBAC) Roles(userID int) ([]string, error) {
+	return b.cached("roles", `SELECT r.name FROM roles r
+		JOIN role_user ru ON ru.role_id = r.id
+		WHERE ru.user_id = ? ORDER BY r.name`, userID)
+}
+
+// Permissions returns the names of all permissions a user has through their roles
+func (b *RBAC) Permissions(userID int) ([]string, error) {
+	return b.cached("permissions", `SELECT DISTINCT p.name FROM permissions p
+		JOIN permission_role pr ON pr.permission_id = p.id
+		JOIN role_user ru ON ru.role_id = pr.role_id
+		WHERE ru.user_id = ? ORDER BY p.name`, userID)
+}
+
+// HasRole reports whether a user has any of roles
+func (b *RBAC) HasRole(userID int, roles ...string) (bool, error) {
+	have, err := b.Roles(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		if contains(have, role) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasPermission reports whether a user has all of permissions
+func (b *RBAC) HasPermission(userID int, permissions ...string) (bool, error) {
+	have, err := b.Permissions(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, permission := range permissions {
+		if !contains(have, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RequireRole returns a middleware that only lets users through who have any
+// of roles, e.g. r.With(app.Auth.RequireRole("admin", "editor")). Guests are
+// handled like in RequireUser; users without the role get a 403.
+func (a *Auth) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return a.requireRBAC(func(id int) (bool, error) {
+		return a.Roles.HasRole(id, roles...)
+	})
+}
+
+// RequirePermission returns a middleware that only lets users through who
+// have all of permissions. Guests are handled like in RequireUser; users
+// without the permissions get a 403.
+func (a *Auth) RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return a.requireRBAC(func(id int) (bool, error) {
+		return a.Roles.HasPermission(id, permissions...)
+	})
+}
+
+func (a *Auth) requireRBAC(allowed func(userID int) (bool, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		check := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a.Roles == nil {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			ok, err := allowed(a.ID(r))
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+
+		return a.RequireUser(check)
+	}
+}
+
+// cached returns the names query selects for a user, from the cache if they
+// are there. The versions are read before the database, so a change made
+// while the query runs moves on to a new key.
+func (b *RBAC) cached(kind, query string, userID int) ([]string, error) {
+	key := ""
+	if b.Cache != nil {
+		if k, err := b.key(kind, userID); err == nil {
+			key = k
+			if v, err := b.Cache.Get(key); err == nil {
+				if list, ok := v.([]string); ok {
+					return list, nil
+				}
+			}
+		}
+	}
+
+	rows, err := b.DB.Query(rebind(b.DatabaseType, query), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		list = append(list, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if key != "" {
+		_ = b.Cache.Set(key, list, b.cacheFor())
+	}
+	return list, nil
+}
+
+func (b *RBAC) cacheFor() int {
+	if b.CacheFor <= 0 {
+		return 3600
+	}
+	return b.CacheFor
+}
+
+// key returns the cache key of a user's roles or permissions at the current versions
+func (b *RBAC) key(kind string, userID int) (string, error) {
+	versions, err := b.Cache.GetMany(b.versionKey(0), b.versionKey(userID))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s:%d:%v.%v", rbacCachePrefix, kind, userID,
+		version(versions[b.versionKey(0)]), version(versions[b.versionKey(userID)])), nil
+}
+
+// versionKey is the cache key of a user's version, or of everybody's for 0
+func (b *RBAC) versionKey(userID int) string {
+	if userID == 0 {
+		return rbacCachePrefix + "version"
+	}
+	return fmt.Sprintf("%sversion:%d", rbacCachePrefix, userID)
+}
+
+// version formats a version read from the cache; a missing one is 0
+func version(v interface{}) interface{} {
+	if v == nil {
+		return 0
+	}
+	return v
+}
+
+// forget moves a user's cached roles and permissions to a new version
+func (b *RBAC) forget(userID int) error {
+	if b.Cache == nil {
+		return nil
+	}
+	return b.bump(b.versionKey(userID))
+}
+
+// forgetAll moves the cached roles and permissions of every user to a new
+// version, for changes that affect everybody with a role
+func (b *RBAC) forgetAll() error {
+	if b.Cache == nil {
+		return nil
+	}
+	return b.bump(b.versionKey(0))
+}
+
+// bump sets a new version. It outlives the lists cached under the previous
+// one, so an expired version can't bring them back.
+func (b *RBAC) bump(key string) error {
+	return b.Cache.Set(key, time.Now().UnixNano(), 2*b.cacheFor())
+}
+
+// insertIgnore adds a row to table, unless it would duplicate a unique key
+func (b *RBAC) insertIgnore(db execer, table, columns string, args ...interface{}) error {
+	query := fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES (%s)", table, columns, placeholders(len(args)))
+	if isPostgres(b.DatabaseType) {
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING", table, columns, placeholders(len(args)))
+	}
+	_, err := db.Exec(rebind(b.DatabaseType, query), args...)
+	return err
+}
+
+// ids looks up the IDs of named roles or permissions, returning unknown if any is missing
+func (b *RBAC) ids(table string, list []string, unknown error) ([]int, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT id, name FROM %s WHERE name IN (%s)", table, placeholders(len(list)))
+	rows, err := b.DB.Query(rebind(b.DatabaseType, query), names(list)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]int, len(list))
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		found[name] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(list))
+	for _, name := range list {
+		id, ok := found[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", unknown, name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// placeholders returns n comma separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func names(list []string) []interface{} {
+	args := make([]interface{}, len(list))
+	for i, name := range list {
+		args[i] = name
+	}
+	return args
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionRememberKey holds the plain remember me token of the current
+// session, so it can be deleted on logout
+const sessionRememberKey = "remember_me_token"
+
+// RememberTokenStore keeps the hashes of remember me tokens
+type RememberTokenStore interface {
+	Create(userID int, hash string) error
+	Exists(userID int, hash string) (bool, error)
+	Delete(hash string) error
+}
+
+// SQLRememberTokenStore keeps remember me tokens in the remember_me_tokens
+// table created by "goravel make auth"
+type SQLRememberTokenStore struct {
+	DB           *sql.DB
+	DatabaseType string
+}
+
+// Create saves a token hash for a user
+func (s *SQLRememberTokenStore) Create(userID int, hash string) error {
+	query := rebind(s.DatabaseType, `INSERT INTO remember_me_tokens (user_id, remember_me_token, created_at, updated_at)
+		VALUES (?, ?, ?, ?)`)
+
+	now := time.Now()
+	_, err := s.DB.Exec(query, userID, hash, now, now)
+	return err
+}
+
+// Exists reports whether a user has a token with the given hash
+func (s *SQLRememberTokenStore) Exists(userID int, hash string) (bool, error) {
+	query := rebind(s.DatabaseType, "SELECT id FROM remember_me_tokens WHERE user_id = ? AND remember_me_token = ?")
+
+	var id int
+	err := s.DB.QueryRow(query, userID, hash).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes the token with the given hash
+func (s *SQLRememberTokenStore) Delete(hash string) error {
+	query := rebind(s.DatabaseType, "DELETE FROM remember_me_tokens WHERE remember_me_token = ?")
+	_, err := s.DB.Exec(query, hash)
+	return err
+}
+
+// RememberMe is a middleware that logs a guest in from their remember me
+// cookie. A cookie whose token is unknown (e.g. the user logged out on
+// another device) is deleted.
+func (a *Auth) RememberMe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.RememberTokens == nil || a.Session.Exists(r.Context(), SessionUserKey) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(a.cookieName())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		userID, token, ok := parseRememberCookie(cookie.Value)
+		if !ok {
+			a.forgetCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		valid, err := a.RememberTokens.Exists(userID, hashToken(token))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !valid {
+			a.forgetCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the user may have been deleted or deactivated since the cookie was set
+		user, err := a.Users.FindByID(userID)
+		if err != nil && !errors.Is(err, ErrUserNotFound) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if err != nil || !active(user) {
+			a.forgetCookie(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if err := a.Session.RenewToken(r.Context()); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		a.Session.Put(r.Context(), SessionUserKey, userID)
+		a.Session.Put(r.Context(), sessionRememberKey, token)
+		setRequestUser(r.Context(), user, nil)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remember creates a remember me token for user and sends it in a cookie
+func (a *Auth) remember(w http.ResponseWriter, r *http.Request, user User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	err = a.RememberTokens.Create(user.AuthID(), hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	lifetime := a.RememberFor
+	if lifetime == 0 {
+		lifetime = 365 * 24 * time.Hour
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.cookieName(),
+		Value:    fmt.Sprintf("%d|%s", user.AuthID(), token),
+		Path:     "/",
+		Expires:  time.Now().Add(lifetime),
+		MaxAge:   int(lifetime.Seconds()),
+		HttpOnly: true,
+		Domain:   a.Session.Cookie.Domain,
+		Secure:   a.Session.Cookie.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	a.Session.Put(r.Context(), sessionRememberKey, token)
+	return nil
+}
+
+// forgetCookie deletes the remember me cookie
+func (a *Auth) forgetCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.cookieName(),
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Now().Add(-100 * time.Hour),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Domain:   a.Session.Cookie.Domain,
+		Secure:   a.Session.Cookie.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (a *Auth) cookieName() string {
+	return fmt.Sprintf("_%s_remember_me", a.AppName)
+}
+
+// parseRememberCookie splits a "user_id|token" cookie value
+func parseRememberCookie(value string) (int, string, bool) {
+	uid, token, found := strings.Cut(value, "|")
+	if !found || token == "" {
+		return 0, "", false
+	}
+
+	id, err := strconv.Atoi(uid)
+	if err != nil {
+		return 0, "", false
+	}
+	return id, token, true
+}
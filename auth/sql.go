@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// isPostgres reports whether databaseType uses numbered bind parameters
+func isPostgres(databaseType string) bool {
+	return databaseType == "postgres" || databaseType == "postgresql" || databaseType == "pgx"
+}
+
+// rebind turns the ? placeholders of query into $1, $2... for postgres
+func rebind(databaseType, query string) string {
+	if !isPostgres(databaseType) {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// randomToken returns a random, URL safe token of 26 characters
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// hashToken returns the hex encoded sha256 of a token. Only hashes are
+// stored, so a leaked table doesn't hand out working tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+var (
+	// ErrTooManyAttempts is returned while an account or IP address is locked
+	// out after failed logins; LoginThrottle.RetryAfter says for how long
+	ErrTooManyAttempts = errors.New("auth: too many login attempts")
+	// ErrCaptchaRequired is returned when a CAPTCHA is required but wasn't solved
+	ErrCaptchaRequired = errors.New("auth: captcha required")
+)
+
+// throttleCachePrefix starts the cache keys of failed login counters and locks
+const throttleCachePrefix = "login:"
+
+// LoginThrottle counts failed logins per account and per IP address. After
+// the second failure on an account, each further attempt has to wait
+// Delay, then twice as long, and so on; after MaxAttempts failures the
+// account is locked for LockoutFor and OnLockout is called. An IP address is
+// locked after MaxIPAttempts failures over any accounts. Counters are
+// forgotten LockoutFor after the last failure.
+//
+// Waiting is enforced by rejecting attempts with ErrTooManyAttempts, not by
+// sleeping, so an attacker can't tie up the server with slow requests.
+type LoginThrottle struct {
+	Cache         cache.Cache   // kept in memory if nil
+	MaxAttempts   int           // failures before an account is locked, 5 if not set
+	MaxIPAttempts int           // failures before an IP address is locked, 20 if not set
+	LockoutFor    time.Duration // 15 minutes if not set
+	Delay         time.Duration // first progressive delay; 0 disables the delays
+
+	// CaptchaAfter is the number of failures on an account or IP address
+	// after which VerifyCaptcha must accept the request; 0 disables it
+	CaptchaAfter  int
+	VerifyCaptcha func(r *http.Request) bool
+
+	// OnLockout is called when an account gets locked, e.g. to email its owner
+	OnLockout func(email string)
+
+	// ErrorLog gets cache errors, which are dropped if it is nil
+	ErrorLog *log.Logger
+
+	mu     sync.Mutex
+	memory map[string]throttleEntry
+}
+
+type throttleEntry struct {
+	value   int64
+	expires time.Time
+}
+
+// AttemptRequest checks an email and password like Attempt, counting failed
+// attempts with Throttle. It returns ErrTooManyAttempts while the account
+// or the request's IP address is locked out, and ErrCaptchaRequired when a
+// CAPTCHA is required and wasn't solved.
+func (a *Auth) AttemptRequest(r *http.Request, email, password string) (User, error) {
+	t := a.Throttle
+	if t == nil {
+		return a.Attempt(email, password)
+	}
+
+	if t.RetryAfter(r, email) > 0 {
+		return nil, ErrTooManyAttempts
+	}
+	if t.VerifyCaptcha != nil && t.CaptchaRequired(r, email) && !t.VerifyCaptcha(r) {
+		return nil, ErrCaptchaRequired
+	}
+
+	user, err := a.Attempt(email, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		t.Fail(r, email)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// with 2FA the failures are only forgotten once the code is right too,
+	// see CompleteTwoFactor
+	enabled, err := a.TwoFactorEnabled(user.AuthID())
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		a.Session.Put(r.Context(), pendingEmailKey, email)
+	} else {
+		t.Clear(email)
+	}
+	return user, nil
+}
+
+// RetryAfter returns how long the account and the request's IP address are
+// still locked out, 0 if they aren't
+func (t *LoginThrottle) RetryAfter(r *http.Request, email string) time.Duration {
+	now := time.Now().UnixNano()
+	until := t.load(t.key("lock", email))
+	if ipUntil := t.load(t.ipKey("lock", r)); ipUntil > until {
+		until = ipUntil
+	}
+
+	if until <= now {
+		return 0
+	}
+	return time.Duration(until - now)
+}
+
+// CaptchaRequired reports whether the account or the request's IP address
+// failed CaptchaAfter times. An empty email only checks the IP address,
+// e.g. to decide whether the login form shows a CAPTCHA.
+func (t *LoginThrottle) CaptchaRequired(r *http.Request, email string) bool {
+	if t.CaptchaAfter <= 0 {
+		return false
+	}
+
+	failures := t.load(t.ipKey("fail", r))
+	if email != "" {
+		if n := t.load(t.key("fail", email)); n > failures {
+			failures = n
+		}
+	}
+	return failures >= int64(t.CaptchaAfter)
+}
+
+// Fail records a failed login
+func (t *LoginThrottle) Fail(r *http.Request, email string) {
+	if t.fail(r, email) && t.OnLockout != nil {
+		t.OnLockout(email)
+	}
+}
+
+// fail counts a failure of account, which is an email or another name for
+// what is being guessed, reporting whether it just got locked
+func (t *LoginThrottle) fail(r *http.Request, account string) (locked bool) {
+	window := t.lockoutFor()
+
+	n := t.increment(t.key("fail", account), window)
+
+	switch {
+	case n >= int64(t.maxAttempts()):
+		t.lock(t.key("lock", account), window)
+		locked = true
+	case t.Delay > 0 && n >= 2:
+		t.lock(t.key("lock", account), t.delay(n))
+	}
+
+	ipn := t.increment(t.ipKey("fail", r), window)
+	if ipn >= int64(t.maxIPAttempts()) {
+		t.lock(t.ipKey("lock", r), window)
+	}
+	return locked
+}
+
+// Clear forgets the failures of an account after a successful login. The
+// IP address keeps its count, so one known password doesn't reset it.
+func (t *LoginThrottle) Clear(email string) {
+	t.forget(t.key("fail", email), t.key("lock", email))
+}
+
+// delay returns the wait after the n-th failure: Delay after the second one,
+// doubling with each further one, but never longer than a lockout
+func (t *LoginThrottle) delay(n int64) time.Duration {
+	d := t.Delay
+	for i := int64(2); i < n && d < t.lockoutFor(); i++ {
+		d *= 2
+	}
+	if d > t.lockoutFor() {
+		return t.lockoutFor()
+	}
+	return d
+}
+
+func (t *LoginThrottle) lock(key string, d time.Duration) {
+	t.store(key, time.Now().Add(d).UnixNano(), d)
+}
+
+func (t *LoginThrottle) key(kind, email string) string {
+	return throttleCachePrefix + kind + ":email:" + emailHash(email)
+}
+
+func (t *LoginThrottle) ipKey(kind string, r *http.Request) string {
+	return throttleCachePrefix + kind + ":ip:" + clientIP(r)
+}
+
+func (t *LoginThrottle) maxAttempts() int {
+	if t.MaxAttempts <= 0 {
+		return 5
+	}
+	return t.MaxAttempts
+}
+
+func (t *LoginThrottle) maxIPAttempts() int {
+	if t.MaxIPAttempts <= 0 {
+		return 20
+	}
+	return t.MaxIPAttempts
+}
+
+func (t *LoginThrottle) lockoutFor() time.Duration {
+	if t.LockoutFor <= 0 {
+		return 15 * time.Minute
+	}
+	return t.LockoutFor
+}
+
+// load reads a counter or lock. With a cache, values kept in memory while
+// it was failing count too, whichever is higher.
+func (t *LoginThrottle) load(key string) int64 {
+	var n int64
+	if t.Cache != nil {
+		if v, err := t.Cache.Get(key); err == nil {
+			n, _ = v.(int64)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.memory[key]
+	if !ok || time.Now().After(e.expires) || e.value < n {
+		return n
+	}
+	return e.value
+}
+
+func (t *LoginThrottle) store(key string, value int64, ttl time.Duration) {
+	if t.Cache != nil {
+		seconds := int((ttl + time.Second - 1) / time.Second)
+		err := t.Cache.Set(key, value, seconds)
+		if err == nil {
+			return
+		}
+		if t.ErrorLog != nil {
+			t.ErrorLog.Println("login throttle: storing in memory, the cache failed:", err)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.prune(now)
+	t.memory[key] = throttleEntry{value: value, expires: now.Add(ttl)}
+}
+
+// prune drops expired entries, so the map doesn't grow forever. t.mu must be held.
+func (t *LoginThrottle) prune(now time.Time) {
+	if t.memory == nil {
+		t.memory = make(map[string]throttleEntry)
+	}
+	for k, e := range t.memory {
+		if now.After(e.expires) {
+			delete(t.memory, k)
+		}
+	}
+}
+
+// increment adds a failure to a counter in one atomic step, so concurrent
+// failed logins are all counted, and restarts its window. If the cache
+// fails, the failure is counted in memory, so logins still get locked.
+func (t *LoginThrottle) increment(key string, ttl time.Duration) int64 {
+	if t.Cache != nil {
+		seconds := int((ttl + time.Second - 1) / time.Second)
+		n, err := t.Cache.Increment(key, 1, seconds)
+		if err == nil {
+			return n
+		}
+		if t.ErrorLog != nil {
+			t.ErrorLog.Println("login throttle: counting in memory, the cache failed:", err)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.prune(now)
+
+	e := t.memory[key]
+	e.value++
+	e.expires = now.Add(ttl)
+	t.memory[key] = e
+	return e.value
+}
+
+func (t *LoginThrottle) forget(keys ...string) {
+	if t.Cache != nil {
+		_ = t.Cache.DeleteMany(keys...)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range keys {
+		delete(t.memory, k)
+	}
+}
+
+// clientIP returns the IP address of a request. Behind a proxy, chi's
+// RealIP middleware, which goravel's router uses, sets RemoteAddr from the
+// forwarding headers.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
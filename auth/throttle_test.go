@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// downCache is a cache.Cache whose backend is unreachable
+type downCache struct{}
+
+var errCacheDown = errors.New("cache is down")
+
+func (downCache) Has(string) (bool, error)                          { return false, errCacheDown }
+func (downCache) Get(string) (interface{}, error)                   { return nil, errCacheDown }
+func (downCache) Set(string, interface{}, ...int) error             { return errCacheDown }
+func (downCache) Delete(string) error                               { return errCacheDown }
+func (downCache) EmptyByMatch(string) error                         { return errCacheDown }
+func (downCache) Prune() error                                      { return errCacheDown }
+func (downCache) GetMany(...string) (map[string]interface{}, error) { return nil, errCacheDown }
+func (downCache) SetMany(map[string]interface{}, ...int) error      { return errCacheDown }
+func (downCache) DeleteMany(...string) error                        { return errCacheDown }
+func (downCache) Add(string, interface{}, ...int) (bool, error)     { return false, errCacheDown }
+func (downCache) Increment(string, int64, ...int) (int64, error)    { return 0, errCacheDown }
+
+func TestLoginThrottleLocksAccount(t *testing.T) {
+	for name, c := range map[string]*LoginThrottle{
+		"memory":     {MaxAttempts: 3},
+		"cache down": {MaxAttempts: 3, Cache: downCache{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/users/login", nil)
+
+			for i := 0; i < 2; i++ {
+				c.Fail(r, "user@example.com")
+			}
+			if d := c.RetryAfter(r, "user@example.com"); d != 0 {
+				t.Fatalf("locked after 2 of 3 failures for %s", d)
+			}
+
+			c.Fail(r, "user@example.com")
+			if d := c.RetryAfter(r, "user@example.com"); d <= 0 || d > 15*time.Minute {
+				t.Fatalf("RetryAfter after 3 failures = %s, want up to 15m", d)
+			}
+
+			c.Clear("user@example.com")
+			if d := c.RetryAfter(r, "user@example.com"); d != 0 {
+				t.Fatalf("still locked for %s after Clear", d)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleCountsConcurrentFailures(t *testing.T) {
+	c := &LoginThrottle{MaxAttempts: 1000, MaxIPAttempts: 1000}
+	r := httptest.NewRequest("POST", "/users/login", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Fail(r, "user@example.com")
+		}()
+	}
+	wg.Wait()
+
+	if n := c.load(c.key("fail", "user@example.com")); n != 50 {
+		t.Fatalf("counted %d of 50 failures", n)
+	}
+}
+
+func TestLoginThrottleDelay(t *testing.T) {
+	c := &LoginThrottle{Delay: time.Second, LockoutFor: 5 * time.Second}
+
+	for n, want := range map[int64]time.Duration{
+		2: time.Second,
+		3: 2 * time.Second,
+		4: 4 * time.Second,
+		5: 5 * time.Second, // capped at LockoutFor
+	} {
+		if got := c.delay(n); got != want {
+			t.Errorf("delay(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when a bearer token is missing, malformed or unknown
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned when a bearer token has expired
+	ErrExpiredToken = errors.New("expired token")
+	// ErrMissingAbility is returned when a token lacks an ability a route requires
+	ErrMissingAbility = errors.New("token is missing a required ability")
+	// ErrInvalidAbility is returned by CreateToken for empty ability names or
+	// names with a comma, which separates the abilities in the database
+	ErrInvalidAbility = errors.New("invalid ability name")
+)
+
+// AllAbilities grants a token every ability
+const AllAbilities = "*"
+
+// lastUsedInterval is how often a token's last used time is written back,
+// so busy tokens don't cost a database write per request
+const lastUsedInterval = time.Minute
+
+// Token is a personal access token. The plain text token is only known when
+// it is created; the store keeps its hash.
+type Token struct {
+	ID         int
+	UserID     int
+	Name       string
+	Hash       string
+	Abilities  []string // what the token may do, e.g. "posts:read"; "*" allows everything
+	Expires    time.Time
+	LastUsedAt time.Time // zero if the token was never used
+	CreatedAt  time.Time
+}
+
+// Can reports whether the token has an ability
+func (t *Token) Can(ability string) bool {
+	for _, a := range t.Abilities {
+		if a == ability || a == AllAbilities {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenStore keeps API tokens
+type TokenStore interface {
+	Create(token *Token) error
+	FindByHash(hash string) (*Token, error)
+	ForUser(userID int) ([]*Token, error)
+	// Touch sets the last used time of a token
+	Touch(id int, usedAt time.Time) error
+	Delete(id int) error
+	DeleteForUser(userID int) error
+}
+
+// SQLTokenStore keeps API tokens in the tokens table created by "goravel make auth"
+type SQLTokenStore struct {
+	DB           *sql.DB
+	DatabaseType string
+}
+
+// Create saves a token, setting its ID
+func (s *SQLTokenStore) Create(token *Token) error {
+	now := time.Now()
+	token.CreatedAt = now
+
+	query := `INSERT INTO tokens (user_id, name, token_hash, abilities, expiry, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{token.UserID, token.Name, token.Hash, strings.Join(token.Abilities, ","), token.Expires, now, now}
+
+	if isPostgres(s.DatabaseType) {
+		return s.DB.QueryRow(rebind(s.DatabaseType, query+" RETURNING id"), args...).Scan(&token.ID)
+	}
+
+	res, err := s.DB.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	token.ID = int(id)
+	return nil
+}
+
+const tokenColumns = "id, user_id, name, token_hash, abilities, expiry, last_used_at, created_at"
+
+// FindByHash returns the token with the given hash
+func (s *SQLTokenStore) FindByHash(hash string) (*Token, error) {
+	query := rebind(s.DatabaseType, "SELECT "+tokenColumns+" FROM tokens WHERE token_hash = ?")
+
+	t, err := scanToken(s.DB.QueryRow(query, hash))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	return t, err
+}
+
+// ForUser returns all tokens of a user
+func (s *SQLTokenStore) ForUser(userID int) ([]*Token, error) {
+	query := rebind(s.DatabaseType, "SELECT "+tokenColumns+" FROM tokens WHERE user_id = ? ORDER BY created_at DESC")
+
+	rows, err := s.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*Token
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Touch sets the last used time of a token
+func (s *SQLTokenStore) Touch(id int, usedAt time.Time) error {
+	_, err := s.DB.Exec(rebind(s.DatabaseType, "UPDATE tokens SET last_used_at = ? WHERE id = ?"), usedAt, id)
+	return err
+}
+
+// Delete removes a token
+func (s *SQLTokenStore) Delete(id int) error {
+	_, err := s.DB.Exec(rebind(s.DatabaseType, "DELETE FROM tokens WHERE id = ?"), id)
+	return err
+}
+
+// DeleteForUser removes all tokens of a user
+func (s *SQLTokenStore) DeleteForUser(userID int) error {
+	_, err := s.DB.Exec(rebind(s.DatabaseType, "DELETE FROM tokens WHERE user_id = ?"), userID)
+	return err
+}
+
+// scanToken reads a row selected with tokenColumns
+func scanToken(row interface{ Scan(...interface{}) error }) (*Token, error) {
+	var t Token
+	var abilities string
+	var lastUsed sql.NullTime
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Hash, &abilities, &t.Expires, &lastUsed, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if abilities != "" {
+		t.Abilities = strings.Split(abilities, ",")
+	}
+	t.LastUsedAt = lastUsed.Time
+	return &t, nil
+}
+
+// CreateToken issues a personal access token for a user, valid for ttl and
+// limited to the given abilities (pass AllAbilities for an unrestricted token).
+// The returned plain text token is what the client sends as
+// "Authorization: Bearer <token>"; it can't be recovered later.
+func (a *Auth) CreateToken(userID int, name string, ttl time.Duration, abilities ...string) (string, *Token, error) {
+	for _, ability := range abilities {
+		if strings.TrimSpace(ability) == "" || strings.Contains(ability, ",") {
+			return "", nil, fmt.Errorf("%w: %q", ErrInvalidAbility, ability)
+		}
+	}
+
+	plain, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	token := &Token{
+		UserID:    userID,
+		Name:      name,
+		Hash:      hashToken(plain),
+		Abilities: abilities,
+		Expires:   time.Now().Add(ttl),
+	}
+
+	if err := a.Tokens.Create(token); err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// AuthenticateToken returns the user owning the bearer token of a request,
+// along with the token itself
+func (a *Auth) AuthenticateToken(r *http.Request) (User, *Token, error) {
+	plain, ok := bearerToken(r)
+	if !ok {
+		return nil, nil, ErrInvalidToken
+	}
+
+	token, err := a.Tokens.FindByHash(hashToken(plain))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if token.Expires.Before(time.Now()) {
+		return nil, nil, ErrExpiredToken
+	}
+
+	user, err := a.Users.FindByID(token.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !active(user) {
+		return nil, nil, ErrUserInactive
+	}
+
+	// the last used time is informational, so failing to write it must not
+	// lock clients out; it is tried again on the next request
+	if now := time.Now(); now.Sub(token.LastUsedAt) > lastUsedInterval {
+		if err := a.Tokens.Touch(token.ID, now); err != nil {
+			a.logError("could not update token last used time:", err)
+		} else {
+			token.LastUsedAt = now
+		}
+	}
+
+	return user, token, nil
+}
+
+// RevokeToken deletes the token with the given plain text value
+func (a *Auth) RevokeToken(plain string) error {
+	token, err := a.Tokens.FindByHash(hashToken(plain))
+	if err != nil {
+		return err
+	}
+	return a.Tokens.Delete(token.ID)
+}
+
+// RevokeTokenByID deletes one of a user's tokens, e.g. from a token management page
+func (a *Auth) RevokeTokenByID(userID, id int) error {
+	tokens, err := a.Tokens.ForUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tokens {
+		if t.ID == id {
+			return a.Tokens.Delete(id)
+		}
+	}
+	return ErrInvalidToken
+}
+
+// RevokeAllTokens deletes every token of a user
+func (a *Auth) RevokeAllTokens(userID int) error {
+	return a.Tokens.DeleteForUser(userID)
+}
+
+// bearerToken returns the token of an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// the RFC's 8 digit codes, cut to the last 6 digits
+	for unix, code := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		step, ok := ValidateTOTP(rfcSecret, code, time.Unix(unix, 0))
+		if !ok {
+			t.Errorf("code %s at %d was rejected", code, unix)
+			continue
+		}
+		if want := unix / totpPeriod; step != want {
+			t.Errorf("code %s at %d matched step %d, want %d", code, unix, step, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := "050471"
+
+	for _, offset := range []time.Duration{-totpPeriod * time.Second, totpPeriod * time.Second} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now.Add(offset)); !ok {
+			t.Errorf("code rejected with the clock off by %s", offset)
+		}
+	}
+	for _, offset := range []time.Duration{-3 * totpPeriod * time.Second, 3 * totpPeriod * time.Second} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now.Add(offset)); ok {
+			t.Errorf("code accepted with the clock off by %s", offset)
+		}
+	}
+}
+
+func TestValidateTOTPFormat(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	if _, ok := ValidateTOTP(rfcSecret, " 050 471 ", now); !ok {
+		t.Error("spaces around and inside the code should be ignored")
+	}
+	for _, code := range []string{"", "05047", "0504712", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("code %q was accepted", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", "050471", now); ok {
+		t.Error("an invalid secret accepted a code")
+	}
+}
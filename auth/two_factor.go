@@ -0,0 +1,425 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTwoFactorRequired is returned by Login when the password was right
+	// but the user still has to enter a code; send them to the challenge page
+	ErrTwoFactorRequired = errors.New("two-factor authentication required")
+	// ErrInvalidTwoFactorCode is returned for a wrong, reused or expired code
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrNoTwoFactorChallenge is returned when there is no pending login to
+	// complete, e.g. because it timed out or had too many wrong codes
+	ErrNoTwoFactorChallenge = errors.New("no pending two-factor challenge")
+	// ErrTwoFactorNotSetUp is returned by TwoFactorStore.Get for users without 2FA
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication is not set up")
+	// ErrTwoFactorEnabled is returned by SetupTwoFactor while 2FA is on; it
+	// has to be disabled before a new secret can be set up
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+)
+
+// session keys of a login waiting for its second factor
+const (
+	pendingUserKey     = "auth.2fa.userID"
+	pendingRememberKey = "auth.2fa.remember"
+	pendingAtKey       = "auth.2fa.at"
+	pendingFailuresKey = "auth.2fa.failures"
+	// pendingEmailKey is the email AttemptRequest checked the password of,
+	// whose failed logins are forgotten once the code is right
+	pendingEmailKey = "auth.2fa.email"
+)
+
+const (
+	// twoFactorTimeout is how long a user has to enter their code after the password
+	twoFactorTimeout = 5 * time.Minute
+	// twoFactorMaxFailures wrong codes cancel the pending login
+	twoFactorMaxFailures = 5
+	// recoveryCodeCount codes are generated at a time
+	recoveryCodeCount = 10
+)
+
+// TwoFactor is a user's TOTP setup
+type TwoFactor struct {
+	UserID        int
+	Secret        string // the confirmed secret codes are checked against
+	PendingSecret string // set by SetupTwoFactor, becomes Secret on ConfirmTwoFactor
+	Enabled       bool   // false between SetupTwoFactor and ConfirmTwoFactor
+}
+
+// Cipher encrypts TOTP secrets at rest; goravel.Encryption is one
+type Cipher interface {
+	Encrypt(text string) (string, error)
+	Decrypt(text string) (string, error)
+}
+
+// TwoFactorStore keeps TOTP secrets and hashed recovery codes
+type TwoFactorStore interface {
+	Get(userID int) (*TwoFactor, error)
+	Save(tf *TwoFactor) error
+	// Delete removes a user's secret and recovery codes
+	Delete(userID int) error
+	// UseStep records that the code of a time step was used, reporting false
+	// if it (or a later one) already was, so a code can't be replayed
+	UseStep(userID int, step int64) (bool, error)
+	// ReplaceRecoveryCodes drops a user's recovery codes and saves new hashes
+	ReplaceRecoveryCodes(userID int, hashes []string) error
+	// UseRecoveryCode marks an unused code as used, reporting whether there was one
+	UseRecoveryCode(userID int, hash string) (bool, error)
+}
+
+// SQLTwoFactorStore keeps 2FA data in the two_factor_auth and
+// two_factor_recovery_codes tables created by "goravel make auth"
+type SQLTwoFactorStore struct {
+	DB           *sql.DB
+	DatabaseType string
+	Cipher       Cipher // encrypts secrets; they are stored as is if nil
+}
+
+// Get returns a user's TOTP setup
+func (s *SQLTwoFactorStore) Get(userID int) (*TwoFactor, error) {
+	query := rebind(s.DatabaseType, "SELECT user_id, secret, pending_secret, enabled FROM two_factor_auth WHERE user_id = ?")
+
+	var tf TwoFactor
+	err := s.DB.QueryRow(query, userID).Scan(&tf.UserID, &tf.Secret, &tf.PendingSecret, &tf.Enabled)
+	if err == sql.ErrNoRows {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tf.Secret, err = s.decrypt(tf.Secret); err != nil {
+		return nil, err
+	}
+	if tf.PendingSecret, err = s.decrypt(tf.PendingSecret); err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// Save inserts or updates a user's TOTP setup
+func (s *SQLTwoFactorStore) Save(tf *TwoFactor) error {
+	secret, err := s.encrypt(tf.Secret)
+	if err != nil {
+		return err
+	}
+	pending, err := s.encrypt(tf.PendingSecret)
+	if err != nil {
+		return err
+	}
+
+	upsert := `ON DUPLICATE KEY UPDATE secret = VALUES(secret), pending_secret = VALUES(pending_secret),
+		enabled = VALUES(enabled), last_step = 0, updated_at = VALUES(updated_at)`
+	if isPostgres(s.DatabaseType) {
+		upsert = `ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, pending_secret = EXCLUDED.pending_secret,
+		enabled = EXCLUDED.enabled, last_step = 0, updated_at = EXCLUDED.updated_at`
+	}
+
+	query := rebind(s.DatabaseType, `INSERT INTO two_factor_auth (user_id, secret, pending_secret, enabled, last_step, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 0, ?, ?) `+upsert)
+
+	now := time.Now()
+	_, err = s.DB.Exec(query, tf.UserID, secret, pending, tf.Enabled, now, now)
+	return err
+}
+
+// encrypt seals a secret with the Cipher; empty secrets are stored as is
+func (s *SQLTwoFactorStore) encrypt(secret string) (string, error) {
+	if s.Cipher == nil || secret == "" {
+		return secret, nil
+	}
+	return s.Cipher.Encrypt(secret)
+}
+
+func (s *SQLTwoFactorStore) decrypt(secret string) (string, error) {
+	if s.Cipher == nil || secret == "" {
+		return secret, nil
+	}
+	return s.Cipher.Decrypt(secret)
+}
+
+// Delete removes a user's secret and recovery codes
+func (s *SQLTwoFactorStore) Delete(userID int) error {
+	if err := s.ReplaceRecoveryCodes(userID, nil); err != nil {
+		return err
+	}
+	_, err := s.DB.Exec(rebind(s.DatabaseType, "DELETE FROM two_factor_auth WHERE user_id = ?"), userID)
+	return err
+}
+
+// UseStep records that the code of a time step was used
+func (s *SQLTwoFactorStore) UseStep(userID int, step int64) (bool, error) {
+	query := rebind(s.DatabaseType, "UPDATE two_factor_auth SET last_step = ? WHERE user_id = ? AND last_step < ?")
+	return affectedOne(s.DB.Exec(query, step, userID, step))
+}
+
+// ReplaceRecoveryCodes drops a user's recovery codes and saves new hashes
+func (s *SQLTwoFactorStore) ReplaceRecoveryCodes(userID int, hashes []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(rebind(s.DatabaseType, "DELETE FROM two_factor_recovery_codes WHERE user_id = ?"), userID)
+	if err != nil {
+		return err
+	}
+
+	insert := rebind(s.DatabaseType, "INSERT INTO two_factor_recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)")
+	for _, hash := range hashes {
+		if _, err := tx.Exec(insert, userID, hash, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks an unused code as used
+func (s *SQLTwoFactorStore) UseRecoveryCode(userID int, hash string) (bool, error) {
+	query := rebind(s.DatabaseType, `UPDATE two_factor_recovery_codes SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`)
+	return affectedOne(s.DB.Exec(query, time.Now(), userID, hash))
+}
+
+// affectedOne reports whether an update changed a row
+func affectedOne(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetupTwoFactor starts enrolling a user: it saves a new pending secret and
+// returns it along with its otpauth:// URI (account is shown in the
+// authenticator app, usually the email). 2FA is only switched on by
+// ConfirmTwoFactor, once the user has proven their app works. While 2FA is
+// enabled it returns ErrTwoFactorEnabled, so a hijacked session can't swap
+// the secret; call DisableTwoFactor first.
+func (a *Auth) SetupTwoFactor(userID int, account string) (secret, uri string, err error) {
+	tf, err := a.TwoFactor.Get(userID)
+	if err != nil && !errors.Is(err, ErrTwoFactorNotSetUp) {
+		return "", "", err
+	}
+	if tf != nil && tf.Enabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+
+	secret, err = GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = a.TwoFactor.Save(&TwoFactor{UserID: userID, PendingSecret: secret})
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, TOTPURI(a.AppName, account, secret), nil
+}
+
+// ConfirmTwoFactor switches 2FA on after checking a code from the user's
+// app against the pending secret, which then becomes the active one. It
+// returns the user's recovery codes, which must be shown to them now: only
+// their hashes are kept.
+func (a *Auth) ConfirmTwoFactor(userID int, code string) ([]string, error) {
+	tf, err := a.TwoFactor.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.PendingSecret == "" {
+		return nil, ErrTwoFactorNotSetUp
+	}
+
+	step, ok := ValidateTOTP(tf.PendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	tf.Secret = tf.PendingSecret
+	tf.PendingSecret = ""
+	tf.Enabled = true
+	if err := a.TwoFactor.Save(tf); err != nil {
+		return nil, err
+	}
+
+	// the confirming code can't be replayed to complete a login
+	if _, err := a.TwoFactor.UseStep(userID, step); err != nil {
+		return nil, err
+	}
+
+	return a.RegenerateRecoveryCodes(userID)
+}
+
+// DisableTwoFactor switches 2FA off for a user
+func (a *Auth) DisableTwoFactor(userID int) error {
+	return a.TwoFactor.Delete(userID)
+}
+
+// TwoFactorEnabled reports whether a user has 2FA switched on
+func (a *Auth) TwoFactorEnabled(userID int) (bool, error) {
+	if a.TwoFactor == nil {
+		return false, nil
+	}
+
+	tf, err := a.TwoFactor.Get(userID)
+	if errors.Is(err, ErrTwoFactorNotSetUp) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return tf.Enabled, nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes, returning the new ones
+func (a *Auth) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		token, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = strings.ToLower(token[:5] + "-" + token[5:10])
+		hashes[i] = hashToken(codes[i])
+	}
+
+	if err := a.TwoFactor.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// PendingTwoFactor reports whether the session has a login waiting for its second factor
+func (a *Auth) PendingTwoFactor(r *http.Request) bool {
+	_, ok := a.pendingUser(r)
+	return ok
+}
+
+// CompleteTwoFactor finishes a login started by Login with a code from the
+// user's authenticator app or one of their recovery codes.
+//
+// Wrong codes are counted by Throttle per user and IP address, like wrong
+// passwords, and it returns ErrTooManyAttempts while they are locked out.
+// The count in the session only cancels one pending login; it starts over
+// with the next one.
+func (a *Auth) CompleteTwoFactor(w http.ResponseWriter, r *http.Request, code string) error {
+	ctx := r.Context()
+
+	userID, ok := a.pendingUser(r)
+	if !ok {
+		a.clearPending(r)
+		return ErrNoTwoFactorChallenge
+	}
+
+	account := twoFactorAccount(userID)
+	if a.Throttle != nil && a.Throttle.RetryAfter(r, account) > 0 {
+		return ErrTooManyAttempts
+	}
+
+	valid, err := a.checkSecondFactor(userID, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		if a.Throttle != nil {
+			a.Throttle.fail(r, account)
+		}
+
+		failures := a.Session.GetInt(ctx, pendingFailuresKey) + 1
+		if failures >= twoFactorMaxFailures {
+			a.clearPending(r)
+			return ErrNoTwoFactorChallenge
+		}
+		a.Session.Put(ctx, pendingFailuresKey, failures)
+		return ErrInvalidTwoFactorCode
+	}
+
+	remember := a.Session.GetBool(ctx, pendingRememberKey)
+	if a.Throttle != nil {
+		a.Throttle.Clear(account)
+		if email := a.Session.GetString(ctx, pendingEmailKey); email != "" {
+			a.Throttle.Clear(email)
+		}
+	}
+	a.clearPending(r)
+
+	user, err := a.Users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return a.login(w, r, user, remember)
+}
+
+// checkSecondFactor accepts a TOTP code that wasn't used before, or an unused recovery code
+func (a *Auth) checkSecondFactor(userID int, code string) (bool, error) {
+	tf, err := a.TwoFactor.Get(userID)
+	if err != nil {
+		return false, err
+	}
+	if !tf.Enabled {
+		return false, nil
+	}
+
+	if step, ok := ValidateTOTP(tf.Secret, code, time.Now()); ok {
+		return a.TwoFactor.UseStep(userID, step)
+	}
+
+	return a.TwoFactor.UseRecoveryCode(userID, hashToken(strings.ToLower(strings.TrimSpace(code))))
+}
+
+// startTwoFactor puts the session in the pending state between password and code
+func (a *Auth) startTwoFactor(r *http.Request, user User, remember bool) error {
+	ctx := r.Context()
+
+	if err := a.Session.RenewToken(ctx); err != nil {
+		return err
+	}
+
+	a.Session.Put(ctx, pendingUserKey, user.AuthID())
+	a.Session.Put(ctx, pendingRememberKey, remember)
+	// as Unix seconds: the session codec can't gob encode a time.Time
+	a.Session.Put(ctx, pendingAtKey, time.Now().Unix())
+	a.Session.Remove(ctx, pendingFailuresKey)
+	return nil
+}
+
+func (a *Auth) pendingUser(r *http.Request) (int, bool) {
+	ctx := r.Context()
+
+	if !a.Session.Exists(ctx, pendingUserKey) {
+		return 0, false
+	}
+	if time.Since(time.Unix(a.Session.GetInt64(ctx, pendingAtKey), 0)) > twoFactorTimeout {
+		return 0, false
+	}
+	return a.Session.GetInt(ctx, pendingUserKey), true
+}
+
+func (a *Auth) clearPending(r *http.Request) {
+	ctx := r.Context()
+	a.Session.Remove(ctx, pendingUserKey)
+	a.Session.Remove(ctx, pendingRememberKey)
+	a.Session.Remove(ctx, pendingAtKey)
+	a.Session.Remove(ctx, pendingFailuresKey)
+	a.Session.Remove(ctx, pendingEmailKey)
+}
+
+// twoFactorAccount names a user's codes for the Throttle, apart from emails
+func twoFactorAccount(userID int) string {
+	return fmt.Sprintf("2fa:%d", userID)
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// memoryTwoFactorStore keeps 2FA data in memory
+type memoryTwoFactorStore struct {
+	setups map[int]*TwoFactor
+	steps  map[int]int64
+}
+
+func (s *memoryTwoFactorStore) Get(userID int) (*TwoFactor, error) {
+	tf, ok := s.setups[userID]
+	if !ok {
+		return nil, ErrTwoFactorNotSetUp
+	}
+	c := *tf
+	return &c, nil
+}
+
+func (s *memoryTwoFactorStore) Save(tf *TwoFactor) error {
+	c := *tf
+	s.setups[tf.UserID] = &c
+	s.steps[tf.UserID] = 0
+	return nil
+}
+
+func (s *memoryTwoFactorStore) Delete(userID int) error {
+	delete(s.setups, userID)
+	return nil
+}
+
+func (s *memoryTwoFactorStore) UseStep(userID int, step int64) (bool, error) {
+	if s.steps[userID] >= step {
+		return false, nil
+	}
+	s.steps[userID] = step
+	return true, nil
+}
+
+func (s *memoryTwoFactorStore) ReplaceRecoveryCodes(int, []string) error { return nil }
+
+func (s *memoryTwoFactorStore) UseRecoveryCode(int, string) (bool, error) { return false, nil }
+
+// browser sends requests through a session middleware, keeping its cookies
+type browser struct {
+	t       *testing.T
+	session *scs.SessionManager
+	cookies []*http.Cookie
+}
+
+func (b *browser) do(fn func(w http.ResponseWriter, r *http.Request)) {
+	r := httptest.NewRequest("POST", "/", nil)
+	for _, c := range b.cookies {
+		r.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	b.session.LoadAndSave(http.HandlerFunc(fn)).ServeHTTP(w, r)
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		b.cookies = cookies
+	}
+}
+
+func newTwoFactorAuth(t *testing.T) (*Auth, string) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := &memoryTwoFactorStore{setups: map[int]*TwoFactor{}, steps: map[int]int64{}}
+	store.setups[1] = &TwoFactor{UserID: 1, Secret: secret, Enabled: true}
+
+	return &Auth{
+		Session:   scs.New(),
+		Users:     memoryUsers{1: {ID: 1, Email: "user@example.com", Active: 1}},
+		TwoFactor: store,
+		Throttle:  &LoginThrottle{MaxAttempts: 5, MaxIPAttempts: 100},
+	}, secret
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	a, secret := newTwoFactorAuth(t)
+	b := &browser{t: t, session: a.Session}
+
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := a.Users.FindByID(1)
+		if err := a.Login(w, r, user, false); !errors.Is(err, ErrTwoFactorRequired) {
+			t.Fatalf("Login = %v, want ErrTwoFactorRequired", err)
+		}
+	})
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		if a.ID(r) != 0 {
+			t.Fatal("logged in before entering a code")
+		}
+		if err := a.CompleteTwoFactor(w, r, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("CompleteTwoFactor with a wrong code = %v, want ErrInvalidTwoFactorCode", err)
+		}
+	})
+
+	code := totpCode(mustDecodeSecret(t, secret), time.Now().Unix()/totpPeriod)
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.CompleteTwoFactor(w, r, code); err != nil {
+			t.Fatalf("CompleteTwoFactor = %v", err)
+		}
+	})
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		if a.ID(r) != 1 {
+			t.Fatalf("logged in as %d, want 1", a.ID(r))
+		}
+	})
+}
+
+func TestTwoFactorCancelsAfterTooManyWrongCodes(t *testing.T) {
+	a, _ := newTwoFactorAuth(t)
+	b := &browser{t: t, session: a.Session}
+
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := a.Users.FindByID(1)
+		if err := a.Login(w, r, user, false); !errors.Is(err, ErrTwoFactorRequired) {
+			t.Fatalf("Login = %v, want ErrTwoFactorRequired", err)
+		}
+	})
+
+	var err error
+	for i := 0; i < twoFactorMaxFailures; i++ {
+		b.do(func(w http.ResponseWriter, r *http.Request) {
+			err = a.CompleteTwoFactor(w, r, "000000")
+		})
+	}
+	if !errors.Is(err, ErrNoTwoFactorChallenge) {
+		t.Fatalf("CompleteTwoFactor after %d wrong codes = %v, want ErrNoTwoFactorChallenge", twoFactorMaxFailures, err)
+	}
+}
+
+func TestTwoFactorCodesAreThrottledAcrossLogins(t *testing.T) {
+	a, secret := newTwoFactorAuth(t)
+	b := &browser{t: t, session: a.Session}
+	user := &DefaultUser{ID: 1, Email: "user@example.com", Active: 1}
+
+	// a session cancels its pending login after 5 wrong codes; logging in
+	// again with the password must not give the attacker 5 more
+	for login := 0; login < 2; login++ {
+		b.do(func(w http.ResponseWriter, r *http.Request) {
+			if err := a.Login(w, r, user, false); !errors.Is(err, ErrTwoFactorRequired) {
+				t.Fatalf("Login = %v, want ErrTwoFactorRequired", err)
+			}
+		})
+		for i := 0; i < 3; i++ {
+			b.do(func(w http.ResponseWriter, r *http.Request) {
+				_ = a.CompleteTwoFactor(w, r, "000000")
+			})
+		}
+	}
+
+	code := totpCode(mustDecodeSecret(t, secret), time.Now().Unix()/totpPeriod)
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		err := a.CompleteTwoFactor(w, r, code)
+		if !errors.Is(err, ErrTooManyAttempts) {
+			t.Fatalf("CompleteTwoFactor with the right code while locked = %v, want ErrTooManyAttempts", err)
+		}
+	})
+}
+
+func TestTwoFactorSuccessClearsFailures(t *testing.T) {
+	a, secret := newTwoFactorAuth(t)
+	b := &browser{t: t, session: a.Session}
+
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		a.Throttle.Fail(r, "user@example.com")
+		user, err := a.AttemptRequest(r, "user@example.com", "")
+		if err == nil || user != nil {
+			t.Fatal("AttemptRequest accepted an empty password")
+		}
+	})
+
+	code := totpCode(mustDecodeSecret(t, secret), time.Now().Unix()/totpPeriod)
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := a.Users.FindByID(1)
+		if err := a.Login(w, r, user, false); !errors.Is(err, ErrTwoFactorRequired) {
+			t.Fatalf("Login = %v, want ErrTwoFactorRequired", err)
+		}
+	})
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		_ = a.CompleteTwoFactor(w, r, "000000")
+	})
+	b.do(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.CompleteTwoFactor(w, r, code); err != nil {
+			t.Fatalf("CompleteTwoFactor = %v", err)
+		}
+		if a.ID(r) != 1 {
+			t.Fatalf("logged in as %d, want 1", a.ID(r))
+		}
+	})
+
+	if n := a.Throttle.load(a.Throttle.key("fail", twoFactorAccount(1))); n != 0 {
+		t.Fatalf("%d wrong codes still counted after logging in", n)
+	}
+}
+
+func mustDecodeSecret(t *testing.T, secret string) []byte {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DefaultUser is the user returned by SQLUserProvider
+type DefaultUser struct {
+	ID        int
+	FirstName string
+	LastName  string
+	Email     string
+	Active    int
+	Password  string
+}
+
+// AuthID returns the user's ID
+func (u *DefaultUser) AuthID() int {
+	return u.ID
+}
+
+// AuthPassword returns the user's password hash
+func (u *DefaultUser) AuthPassword() string {
+	return u.Password
+}
+
+// IsActive reports whether the user is active (user_active = 1)
+func (u *DefaultUser) IsActive() bool {
+	return u.Active == 1
+}
+
+// SQLUserProvider finds users in the users table created by "goravel make auth"
+type SQLUserProvider struct {
+	DB           *sql.DB
+	DatabaseType string
+}
+
+const userColumns = "id, first_name, last_name, email, user_active, password"
+
+// FindByID returns the user with the given ID
+func (p *SQLUserProvider) FindByID(id int) (User, error) {
+	return p.findOne(rebind(p.DatabaseType, "SELECT "+userColumns+" FROM users WHERE id = ?"), id)
+}
+
+// FindByEmail returns the user with the given email
+func (p *SQLUserProvider) FindByEmail(email string) (User, error) {
+	return p.findOne(rebind(p.DatabaseType, "SELECT "+userColumns+" FROM users WHERE email = ?"), email)
+}
+
+// UpdatePassword stores a new password hash for a user
+func (p *SQLUserProvider) UpdatePassword(userID int, hash string) error {
+	_, err := p.DB.Exec(rebind(p.DatabaseType, "UPDATE users SET password = ?, updated_at = ? WHERE id = ?"),
+		hash, time.Now(), userID)
+	return err
+}
+
+func (p *SQLUserProvider) findOne(query string, arg interface{}) (User, error) {
+	var u DefaultUser
+	err := p.DB.QueryRow(query, arg).Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.Active, &u.Password)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/saalikmubeen/goravel/cache"
+	"github.com/saalikmubeen/goravel/urlsigner"
+)
+
+var (
+	// ErrInvalidVerificationLink is returned for links that weren't signed by
+	// us, or that belong to an email the user has since changed
+	ErrInvalidVerificationLink = errors.New("auth: invalid verification link")
+	// ErrVerificationLinkExpired is returned for links older than ExpiresIn
+	ErrVerificationLinkExpired = errors.New("auth: verification link expired")
+	// ErrVerificationThrottled is returned when a verification email was sent too recently
+	ErrVerificationThrottled = errors.New("auth: verification email sent too recently")
+)
+
+// sessionVerifiedKey remembers which user of a session is known to be
+// verified, so RequireVerified doesn't hit the database on every request
+const sessionVerifiedKey = "auth.verified"
+
+// VerificationStore reads and updates whether users verified their email
+type VerificationStore interface {
+	// Status returns a user's current email and whether it is verified
+	Status(userID int) (email string, verified bool, err error)
+	MarkVerified(userID int) error
+}
+
+// SQLVerificationStore keeps the verification time in the verified_at
+// column of the users table created by "goravel make auth"
+type SQLVerificationStore struct {
+	DB           *sql.DB
+	DatabaseType string
+}
+
+// Status returns a user's current email and whether it is verified
+func (s *SQLVerificationStore) Status(userID int) (string, bool, error) {
+	var email string
+	var verifiedAt sql.NullTime
+
+	err := s.DB.QueryRow(rebind(s.DatabaseType, "SELECT email, verified_at FROM users WHERE id = ?"), userID).
+		Scan(&email, &verifiedAt)
+	if err == sql.ErrNoRows {
+		return "", false, ErrUserNotFound
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return email, verifiedAt.Valid, nil
+}
+
+// MarkVerified records that a user verified their email now
+func (s *SQLVerificationStore) MarkVerified(userID int) error {
+	now := time.Now()
+	_, err := s.DB.Exec(rebind(s.DatabaseType, "UPDATE users SET verified_at = ?, updated_at = ? WHERE id = ?"),
+		now, now, userID)
+	return err
+}
+
+// EmailVerification sends users a signed, expiring link to confirm their
+// email address, and checks the link when they follow it. The link names
+// the user and a hash of their email, so it stops working if the email
+// changes before it is used.
+type EmailVerification struct {
+	Signer      *urlsigner.Signer
+	URL         string        // the verification route, e.g. https://example.com/users/verify-email
+	ExpiresIn   int           // minutes a link stays valid, 60 if not set
+	ResendEvery time.Duration // minimum time between emails to a user, a minute if not set
+	Store       VerificationStore
+	Cache       cache.Cache // remembers recent emails; kept in memory if nil
+	NoticeURL   string      // where RequireVerified sends unverified users; they get a 403 if empty
+
+	mu   sync.Mutex
+	sent map[int]time.Time
+}
+
+// Link returns a signed verification link for a user
+func (v *EmailVerification) Link(userID int, email string) string {
+	link := fmt.Sprintf("%s?id=%d&email=%s", v.URL, userID, emailHash(email))
+	return v.Signer.GenerateTokenFromString(link)
+}
+
+// Send calls send with a fresh verification link for a user, unless one was
+// sent less than ResendEvery ago, in which case it returns
+// ErrVerificationThrottled. send usually emails the link.
+func (v *EmailVerification) Send(userID int, email string, send func(link string) error) error {
+	if v.throttled(userID) {
+		return ErrVerificationThrottled
+	}
+
+	if err := send(v.Link(userID, email)); err != nil {
+		return err
+	}
+
+	v.markSent(userID)
+	return nil
+}
+
+// Verify checks the link of a request to the verification route, marks its
+// user as verified, and returns the user's ID. Following a link again after
+// the user was verified is not an error.
+func (v *EmailVerification) Verify(r *http.Request) (int, error) {
+	link := v.URL + "?" + r.URL.RawQuery
+	if !v.Signer.VerifyToken(link) {
+		return 0, ErrInvalidVerificationLink
+	}
+	if v.Signer.Expired(link, v.expiresIn()) {
+		return 0, ErrVerificationLinkExpired
+	}
+
+	q := r.URL.Query()
+	userID, err := strconv.Atoi(q.Get("id"))
+	if err != nil {
+		return 0, ErrInvalidVerificationLink
+	}
+
+	email, verified, err := v.Store.Status(userID)
+	if errors.Is(err, ErrUserNotFound) {
+		return 0, ErrInvalidVerificationLink
+	}
+	if err != nil {
+		return 0, err
+	}
+	if emailHash(email) != q.Get("email") {
+		return 0, ErrInvalidVerificationLink
+	}
+
+	if !verified {
+		if err := v.Store.MarkVerified(userID); err != nil {
+			return 0, err
+		}
+	}
+	return userID, nil
+}
+
+// IsVerified reports whether a user verified their current email
+func (v *EmailVerification) IsVerified(userID int) (bool, error) {
+	_, verified, err := v.Store.Status(userID)
+	return verified, err
+}
+
+// RequireVerified is a middleware that only lets users through who verified
+// their email. Guests are handled like in RequireUser; unverified users are
+// sent to Verification.NoticeURL. It lets everybody in when email
+// verification isn't set up.
+func (a *Auth) RequireVerified(next http.Handler) http.Handler {
+	check := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Verification == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// session users are only looked up until they are known to be verified
+		_, tokenUser := UserFromContext(r.Context())
+		id := a.ID(r)
+		if !tokenUser && a.Session.GetInt(r.Context(), sessionVerifiedKey) == id {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		verified, err := a.Verification.IsVerified(id)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !verified {
+			if a.Verification.NoticeURL != "" {
+				http.Redirect(w, r, a.Verification.NoticeURL, http.StatusSeeOther)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		if !tokenUser {
+			a.Session.Put(r.Context(), sessionVerifiedKey, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+
+	return a.RequireUser(check)
+}
+
+func (v *EmailVerification) expiresIn() int {
+	if v.ExpiresIn <= 0 {
+		return 60
+	}
+	return v.ExpiresIn
+}
+
+func (v *EmailVerification) resendEvery() time.Duration {
+	if v.ResendEvery <= 0 {
+		return time.Minute
+	}
+	return v.ResendEvery
+}
+
+func (v *EmailVerification) throttleKey(userID int) string {
+	return fmt.Sprintf("verify-email:%d", userID)
+}
+
+// throttled reports whether a user was sent an email less than ResendEvery ago
+func (v *EmailVerification) throttled(userID int) bool {
+	if v.Cache != nil {
+		found, _ := v.Cache.Has(v.throttleKey(userID))
+		return found
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return time.Since(v.sent[userID]) < v.resendEvery()
+}
+
+func (v *EmailVerification) markSent(userID int) {
+	if v.Cache != nil {
+		seconds := int(v.resendEvery() / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		_ = v.Cache.Set(v.throttleKey(userID), true, seconds)
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.sent == nil {
+		v.sent = make(map[int]time.Time)
+	}
+
+	// drop entries that no longer throttle anything, so the map doesn't grow forever
+	for id, at := range v.sent {
+		if time.Since(at) >= v.resendEvery() {
+			delete(v.sent, id)
+		}
+	}
+	v.sent[userID] = time.Now()
+}
+
+// emailHash binds a verification link to an email address
+func emailHash(email string) string {
+	return hashToken(strings.ToLower(strings.TrimSpace(email)))[:16]
+}
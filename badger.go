@@ -0,0 +1,171 @@
+package goravel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+// loadBadgerConfig reads the badger settings from the environment
+func loadBadgerConfig() badgerConfig {
+	gcRatio, err := strconv.ParseFloat(os.Getenv("BADGER_GC_RATIO"), 64)
+	if err != nil || gcRatio <= 0 || gcRatio >= 1 {
+		gcRatio = 0.7
+	}
+
+	gcSchedule := os.Getenv("BADGER_GC_SCHEDULE")
+	if gcSchedule == "" {
+		gcSchedule = "@daily"
+	}
+
+	return badgerConfig{
+		path:              os.Getenv("BADGER_PATH"),
+		inMemory:          envBool("BADGER_IN_MEMORY", false),
+		valueLogFileSize:  int64(envInt("BADGER_VALUE_LOG_FILE_SIZE", 0)) << 20,
+		valueLogMaxEntry:  uint32(envInt("BADGER_VALUE_LOG_MAX_ENTRIES", 0)),
+		memTableSize:      int64(envInt("BADGER_MEM_TABLE_SIZE", 0)) << 20,
+		gcSchedule:        gcSchedule,
+		gcRatio:           gcRatio,
+		logLevel:          strings.ToLower(os.Getenv("BADGER_LOG_LEVEL")),
+		encrypt:           envBool("BADGER_ENCRYPT", false),
+		keyRotationPeriod: envSeconds("BADGER_KEY_ROTATION", 0),
+	}
+}
+
+// badgerOptions turns the badger settings into badger.Options
+func (g *Goravel) badgerOptions() (badger.Options, error) {
+	cfg := g.config.badger
+
+	path := cfg.path
+	if path == "" {
+		path = "tmp/badger"
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.RootPath, path)
+	}
+
+	opts := badger.DefaultOptions(path)
+
+	if cfg.inMemory {
+		// an in-memory database has no directory at all
+		opts = badger.DefaultOptions("").WithInMemory(true)
+	}
+
+	if cfg.valueLogFileSize > 0 {
+		opts = opts.WithValueLogFileSize(cfg.valueLogFileSize)
+	}
+	if cfg.valueLogMaxEntry > 0 {
+		opts = opts.WithValueLogMaxEntries(cfg.valueLogMaxEntry)
+	}
+	if cfg.memTableSize > 0 {
+		opts = opts.WithMemTableSize(cfg.memTableSize)
+	}
+
+	switch cfg.logLevel {
+	case "none":
+		opts = opts.WithLogger(nil)
+	case "debug":
+		opts = opts.WithLogger(&badgerLogger{g: g}).WithLoggingLevel(badger.DEBUG)
+	case "info":
+		opts = opts.WithLogger(&badgerLogger{g: g}).WithLoggingLevel(badger.INFO)
+	case "", "warning":
+		opts = opts.WithLogger(&badgerLogger{g: g}).WithLoggingLevel(badger.WARNING)
+	case "error":
+		opts = opts.WithLogger(&badgerLogger{g: g}).WithLoggingLevel(badger.ERROR)
+	default:
+		return opts, fmt.Errorf("unknown BADGER_LOG_LEVEL %q", cfg.logLevel)
+	}
+
+	if cfg.encrypt {
+		switch len(g.EncryptionKey) {
+		case 16, 24, 32:
+		default:
+			return opts, errors.New("badger encryption needs KEY to be 16, 24 or 32 characters long")
+		}
+
+		// badger requires an index cache once encryption is on
+		opts = opts.WithEncryptionKey([]byte(g.EncryptionKey)).WithIndexCacheSize(100 << 20)
+		if cfg.keyRotationPeriod > 0 {
+			opts = opts.WithEncryptionKeyRotationDuration(cfg.keyRotationPeriod)
+		}
+	}
+
+	return opts, nil
+}
+
+func (g *Goravel) createBadgerConn() (*badger.DB, error) {
+	opts, err := g.badgerOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not open badger database: %w", err)
+	}
+	return db, nil
+}
+
+func (g *Goravel) createBadgerCache() (*cache.BadgerCache, error) {
+	conn, err := g.createBadgerConn()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheClient := cache.BadgerCache{
+		Conn: conn,
+	}
+	return &cacheClient, nil
+}
+
+// scheduleBadgerGC runs the value log garbage collector on the configured schedule
+func (g *Goravel) scheduleBadgerGC(db *badger.DB) error {
+	if g.config.badger.inMemory {
+		// there is no value log to collect
+		return nil
+	}
+
+	_, err := g.Scheduler.AddFunc(g.config.badger.gcSchedule, func() {
+		// a single run rewrites at most one file, so keep going until
+		// badger reports there is nothing left worth rewriting
+		for {
+			if err := db.RunValueLogGC(g.config.badger.gcRatio); err != nil {
+				if !errors.Is(err, badger.ErrNoRewrite) {
+					g.ErrorLog.Println("badger value log GC:", err)
+				}
+				return
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("invalid BADGER_GC_SCHEDULE: %w", err)
+	}
+	return nil
+}
+
+// badgerLogger sends badger's log output to the application's loggers
+type badgerLogger struct {
+	g *Goravel
+}
+
+func (l *badgerLogger) Errorf(f string, v ...interface{}) {
+	l.g.ErrorLog.Printf("badger: "+strings.TrimSuffix(f, "\n"), v...)
+}
+
+func (l *badgerLogger) Warningf(f string, v ...interface{}) {
+	l.g.ErrorLog.Printf("badger: "+strings.TrimSuffix(f, "\n"), v...)
+}
+
+func (l *badgerLogger) Infof(f string, v ...interface{}) {
+	l.g.InfoLog.Printf("badger: "+strings.TrimSuffix(f, "\n"), v...)
+}
+
+func (l *badgerLogger) Debugf(f string, v ...interface{}) {
+	l.g.InfoLog.Printf("badger: "+strings.TrimSuffix(f, "\n"), v...)
+}
@@ -74,7 +74,99 @@ func (b *BadgerCache) Set(key string, value interface{}, expires ...int) error {
 		})
 	}
 
-	return nil
+	return err
+}
+
+// Add stores a key if it doesn't exist yet. Badger aborts a transaction
+// with ErrConflict when another one wrote the key after it was read, so the
+// check is retried until it sees the winner's write.
+func (b *BadgerCache) Add(key string, value interface{}, expires ...int) (bool, error) {
+	entry := Entry{}
+	entry[key] = value
+	encoded, err := encode(entry)
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		added := false
+		err := b.Conn.Update(func(txn *badger.Txn) error {
+			_, err := txn.Get([]byte(key))
+			if err == nil {
+				return nil
+			}
+			if err != badger.ErrKeyNotFound {
+				return err
+			}
+
+			e := badger.NewEntry([]byte(key), encoded)
+			if len(expires) > 0 {
+				e = e.WithTTL(time.Second * time.Duration(expires[0]))
+			}
+			added = true
+			return txn.SetEntry(e)
+		})
+		if err == badger.ErrConflict {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return added, nil
+	}
+}
+
+// Increment updates a counter in a transaction, retrying it when it
+// conflicts with another write of the key
+func (b *BadgerCache) Increment(key string, by int64, expires ...int) (int64, error) {
+	for {
+		var n int64
+		err := b.Conn.Update(func(txn *badger.Txn) error {
+			var expiresAt uint64
+			n = 0
+
+			item, err := txn.Get([]byte(key))
+			switch {
+			case err == badger.ErrKeyNotFound:
+			case err != nil:
+				return err
+			default:
+				fromCache, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				decoded, err := decode(fromCache)
+				if err != nil {
+					return err
+				}
+				n, _ = decoded[key].(int64)
+				expiresAt = item.ExpiresAt()
+			}
+			n += by
+
+			entry := Entry{}
+			entry[key] = n
+			encoded, err := encode(entry)
+			if err != nil {
+				return err
+			}
+
+			e := badger.NewEntry([]byte(key), encoded)
+			if len(expires) > 0 {
+				e = e.WithTTL(time.Second * time.Duration(expires[0]))
+			} else if expiresAt > 0 {
+				e.ExpiresAt = expiresAt
+			}
+			return txn.SetEntry(e)
+		})
+		if err == badger.ErrConflict {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
 }
 
 func (b *BadgerCache) Delete(key string) error {
@@ -150,3 +242,112 @@ func (b *BadgerCache) emptyByMatch(prefix string) error {
 
 	return err
 }
+
+// GetMany retrieves several keys from the cache inside a single read transaction.
+// Keys that don't exist in the cache are left out of the returned map.
+func (b *BadgerCache) GetMany(keys ...string) (map[string]interface{}, error) {
+	items := make(map[string]interface{}, len(keys))
+
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			item, err := txn.Get([]byte(key))
+			if err == badger.ErrKeyNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			fromCache, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			decoded, err := decode(fromCache)
+			if err != nil {
+				return err
+			}
+
+			items[key] = decoded[key]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// SetMany stores several keys in the cache using a write batch, which
+// splits the entries into as many transactions as badger needs.
+func (b *BadgerCache) SetMany(values map[string]interface{}, expires ...int) error {
+	wb := b.Conn.NewWriteBatch()
+	defer wb.Cancel()
+
+	for key, value := range values {
+		entry := Entry{}
+		entry[key] = value
+		encoded, err := encode(entry)
+		if err != nil {
+			return err
+		}
+
+		e := badger.NewEntry([]byte(key), encoded)
+		if len(expires) > 0 {
+			e = e.WithTTL(time.Second * time.Duration(expires[0]))
+		}
+
+		if err := wb.SetEntry(e); err != nil {
+			return err
+		}
+	}
+
+	return wb.Flush()
+}
+
+// DeleteMany removes several keys from the cache using a write batch
+func (b *BadgerCache) DeleteMany(keys ...string) error {
+	wb := b.Conn.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, key := range keys {
+		if err := wb.Delete([]byte(key)); err != nil {
+			return err
+		}
+	}
+
+	return wb.Flush()
+}
+
+// expiries returns when keys expire. Keys without an expiry get the zero
+// time, missing keys the current time.
+func (b *BadgerCache) expiries(keys ...string) (map[string]time.Time, error) {
+	now := time.Now()
+	expiries := make(map[string]time.Time, len(keys))
+
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			item, err := txn.Get([]byte(key))
+			if err == badger.ErrKeyNotFound {
+				expiries[key] = now
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if at := item.ExpiresAt(); at > 0 {
+				expiries[key] = time.Unix(int64(at), 0)
+			} else {
+				expiries[key] = time.Time{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expiries, nil
+}
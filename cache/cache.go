@@ -7,6 +7,20 @@ type Cache interface {
 	Delete(string) error
 	EmptyByMatch(string) error
 	Prune() error
+
+	// batch operations, so that pages needing many keys don't pay a round trip for each one
+	GetMany(...string) (map[string]interface{}, error)
+	SetMany(map[string]interface{}, ...int) error
+	DeleteMany(...string) error
+
+	// Add stores a key only if it doesn't exist yet, in one atomic step, and
+	// reports whether it did; e.g. to claim a one-time token exactly once
+	Add(string, interface{}, ...int) (bool, error)
+
+	// Increment adds to the int64 counter at a key, which starts at 0, in one
+	// atomic step and returns the new count. The expiry, if given, restarts;
+	// otherwise the counter keeps the one it has.
+	Increment(string, int64, ...int) (int64, error)
 }
 
 // Entry is a map of string to interface
@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NearCache is a two-tier cache. It keeps an in-process LRU of recently read
+// keys in front of any other Cache implementation, so hot keys are served
+// without a network round trip. Writes always go to the backend and evict the
+// local copy; when an Invalidator is set, the eviction is broadcast so every
+// app instance drops its own copy as well.
+type NearCache struct {
+	Backend     Cache
+	Invalidator Invalidator
+
+	size int           // maximum number of keys kept locally
+	ttl  time.Duration // how long a key may live locally, 0 means until evicted
+
+	mu    sync.Mutex
+	ll    *list.List // front is most recently used
+	items map[string]*list.Element
+	// generation is bumped on every invalidation, so a Get that raced with an
+	// invalidation doesn't put a stale value back into the local cache
+	generation uint64
+}
+
+type nearEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// Invalidator broadcasts cache invalidations between app instances
+type Invalidator interface {
+	// Publish sends an invalidation message to every subscriber, including this one
+	Publish(message string) error
+	// Subscribe calls handler for every message published by any instance.
+	// It blocks until the invalidator is closed.
+	Subscribe(handler func(message string)) error
+	Close() error
+}
+
+// expiryReader is implemented by backends that can tell when keys expire,
+// so local copies never outlive them. Keys without an expiry map to the zero time.
+type expiryReader interface {
+	expiries(keys ...string) (map[string]time.Time, error)
+}
+
+// invalidation messages are the affected key or prefix with a one letter tag
+const (
+	invalidateKey    = "k:"
+	invalidatePrefix = "p:"
+)
+
+// NewNearCache wraps backend with a local LRU holding at most size keys for up
+// to ttl each, and never past their expiry in the backend. If invalidator is not nil, it starts listening for invalidations
+// published by other instances.
+func NewNearCache(backend Cache, size int, ttl time.Duration, invalidator Invalidator) *NearCache {
+	if size <= 0 {
+		size = 1000
+	}
+
+	n := &NearCache{
+		Backend:     backend,
+		Invalidator: invalidator,
+		size:        size,
+		ttl:         ttl,
+		ll:          list.New(),
+		items:       make(map[string]*list.Element),
+	}
+
+	if invalidator != nil {
+		go func() {
+			_ = invalidator.Subscribe(n.handleInvalidation)
+		}()
+	}
+
+	return n
+}
+
+// Has checks if a key exists in the local cache or the backend
+func (n *NearCache) Has(key string) (bool, error) {
+	if _, ok := n.getLocal(key); ok {
+		return true, nil
+	}
+	return n.Backend.Has(key)
+}
+
+// Get returns a key from the local cache, falling back to the backend on a miss
+func (n *NearCache) Get(key string) (interface{}, error) {
+	if value, ok := n.getLocal(key); ok {
+		return value, nil
+	}
+
+	generation := n.currentGeneration()
+
+	value, err := n.Backend.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiries, ok := n.backendExpiries(key); ok {
+		n.setLocal(key, value, expiries[key], generation)
+	}
+
+	return value, nil
+}
+
+// Set stores a key in the backend and invalidates it everywhere
+func (n *NearCache) Set(key string, value interface{}, expires ...int) error {
+	if err := n.Backend.Set(key, value, expires...); err != nil {
+		return err
+	}
+	return n.invalidate(invalidateKey + key)
+}
+
+// Add stores a key in the backend if it doesn't exist there yet and, if it
+// was added, invalidates it everywhere
+func (n *NearCache) Add(key string, value interface{}, expires ...int) (bool, error) {
+	added, err := n.Backend.Add(key, value, expires...)
+	if err != nil || !added {
+		return added, err
+	}
+	return true, n.invalidate(invalidateKey + key)
+}
+
+// Increment updates a counter in the backend and invalidates it everywhere
+func (n *NearCache) Increment(key string, by int64, expires ...int) (int64, error) {
+	count, err := n.Backend.Increment(key, by, expires...)
+	if err != nil {
+		return 0, err
+	}
+	return count, n.invalidate(invalidateKey + key)
+}
+
+// Delete removes a key from the backend and invalidates it everywhere
+func (n *NearCache) Delete(key string) error {
+	if err := n.Backend.Delete(key); err != nil {
+		return err
+	}
+	return n.invalidate(invalidateKey + key)
+}
+
+// EmptyByMatch removes all keys starting with prefix from the backend and invalidates them everywhere
+func (n *NearCache) EmptyByMatch(prefix string) error {
+	if err := n.Backend.EmptyByMatch(prefix); err != nil {
+		return err
+	}
+	return n.invalidate(invalidatePrefix + prefix)
+}
+
+// Prune empties the backend and every local cache
+func (n *NearCache) Prune() error {
+	if err := n.Backend.Prune(); err != nil {
+		return err
+	}
+	return n.invalidate(invalidatePrefix)
+}
+
+// GetMany returns the keys found locally and fetches the rest from the backend in one batch
+func (n *NearCache) GetMany(keys ...string) (map[string]interface{}, error) {
+	items := make(map[string]interface{}, len(keys))
+	var missing []string
+
+	for _, key := range keys {
+		if value, ok := n.getLocal(key); ok {
+			items[key] = value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return items, nil
+	}
+
+	generation := n.currentGeneration()
+
+	fromBackend, err := n.Backend.GetMany(missing...)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]string, 0, len(fromBackend))
+	for key, value := range fromBackend {
+		items[key] = value
+		found = append(found, key)
+	}
+
+	if expiries, ok := n.backendExpiries(found...); ok {
+		for _, key := range found {
+			n.setLocal(key, items[key], expiries[key], generation)
+		}
+	}
+
+	return items, nil
+}
+
+// SetMany stores several keys in the backend and invalidates them everywhere
+func (n *NearCache) SetMany(values map[string]interface{}, expires ...int) error {
+	if err := n.Backend.SetMany(values, expires...); err != nil {
+		return err
+	}
+
+	for key := range values {
+		if err := n.invalidate(invalidateKey + key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMany removes several keys from the backend and invalidates them everywhere
+func (n *NearCache) DeleteMany(keys ...string) error {
+	if err := n.Backend.DeleteMany(keys...); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := n.invalidate(invalidateKey + key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops listening for invalidations
+func (n *NearCache) Close() error {
+	if n.Invalidator == nil {
+		return nil
+	}
+	return n.Invalidator.Close()
+}
+
+// invalidate evicts locally and then tells the other instances to do the same.
+// Evicting first means this instance never serves a stale value, even if publishing fails.
+func (n *NearCache) invalidate(message string) error {
+	n.handleInvalidation(message)
+
+	if n.Invalidator == nil {
+		return nil
+	}
+	return n.Invalidator.Publish(message)
+}
+
+// handleInvalidation evicts the key or prefix named in an invalidation message
+func (n *NearCache) handleInvalidation(message string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.generation++
+
+	switch {
+	case strings.HasPrefix(message, invalidateKey):
+		if el, ok := n.items[strings.TrimPrefix(message, invalidateKey)]; ok {
+			n.removeElement(el)
+		}
+
+	case strings.HasPrefix(message, invalidatePrefix):
+		prefix := strings.TrimPrefix(message, invalidatePrefix)
+		for key, el := range n.items {
+			if strings.HasPrefix(key, prefix) {
+				n.removeElement(el)
+			}
+		}
+	}
+}
+
+func (n *NearCache) currentGeneration() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.generation
+}
+
+func (n *NearCache) getLocal(key string) (interface{}, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	el, ok := n.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*nearEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		n.removeElement(el)
+		return nil, false
+	}
+
+	n.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+// backendExpiries returns when keys expire in the backend. Backends that
+// can't tell report no expiry, leaving the local ttl as the only limit.
+// ok is false if the expiries couldn't be read, so nothing is kept locally.
+func (n *NearCache) backendExpiries(keys ...string) (map[string]time.Time, bool) {
+	reader, ok := n.Backend.(expiryReader)
+	if !ok || len(keys) == 0 {
+		return map[string]time.Time{}, true
+	}
+
+	expiries, err := reader.expiries(keys...)
+	if err != nil {
+		return nil, false
+	}
+	return expiries, true
+}
+
+// setLocal stores a value read from the backend until the earlier of the
+// local ttl and backendExpires, unless an invalidation happened since
+// generation was taken
+func (n *NearCache) setLocal(key string, value interface{}, backendExpires time.Time, generation uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if generation != n.generation {
+		return
+	}
+
+	var expires time.Time
+	if n.ttl > 0 {
+		expires = time.Now().Add(n.ttl)
+	}
+	if !backendExpires.IsZero() && (expires.IsZero() || backendExpires.Before(expires)) {
+		expires = backendExpires
+	}
+	if !expires.IsZero() && !time.Now().Before(expires) {
+		return
+	}
+
+	if el, ok := n.items[key]; ok {
+		entry := el.Value.(*nearEntry)
+		entry.value = value
+		entry.expires = expires
+		n.ll.MoveToFront(el)
+		return
+	}
+
+	n.items[key] = n.ll.PushFront(&nearEntry{key: key, value: value, expires: expires})
+
+	for n.ll.Len() > n.size {
+		n.removeElement(n.ll.Back())
+	}
+}
+
+func (n *NearCache) removeElement(el *list.Element) {
+	n.ll.Remove(el)
+	delete(n.items, el.Value.(*nearEntry).key)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"fmt"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
@@ -14,11 +15,12 @@ type RedisCache struct {
 }
 
 // Has checks if a key exists in the cache
-func (c *RedisCache) Has(key string) (bool, error) {
+func (c *RedisCache) Has(str string) (bool, error) {
+	key := fmt.Sprintf("%s:%s", c.Prefix, str)
 	conn := c.Conn.Get()
 	defer conn.Close()
 
-	exists, err := redis.Bool(conn.Do("EXISTS", c.Prefix+key))
+	exists, err := redis.Bool(conn.Do("EXISTS", key))
 	if err != nil {
 		return false, err
 	}
@@ -99,6 +101,126 @@ func (c *RedisCache) Set(str string, value interface{}, expires ...int) error {
 	return nil
 }
 
+// Add stores a key with SET NX, so only one of several concurrent callers
+// gets true
+func (c *RedisCache) Add(str string, value interface{}, expires ...int) (bool, error) {
+	key := fmt.Sprintf("%s:%s", c.Prefix, str)
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	entry := Entry{}
+	entry[key] = value
+	encoded, err := encode(entry)
+	if err != nil {
+		return false, err
+	}
+
+	args := []interface{}{key, string(encoded), "NX"}
+	if len(expires) > 0 {
+		args = append(args, "EX", expires[0])
+	}
+
+	// the reply is OK when the key was set and nil when it existed already
+	_, err = redis.String(conn.Do("SET", args...))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Increment updates a counter in an optimistic transaction: the key is
+// WATCHed while it is read, and EXEC fails if another client changed it in
+// the meantime, in which case the increment starts over.
+func (c *RedisCache) Increment(str string, by int64, expires ...int) (int64, error) {
+	key := fmt.Sprintf("%s:%s", c.Prefix, str)
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	for {
+		n, ttl, err := c.readCounter(conn, key)
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return 0, err
+		}
+		n += by
+
+		entry := Entry{}
+		entry[key] = n
+		encoded, err := encode(entry)
+		if err != nil {
+			_, _ = conn.Do("UNWATCH")
+			return 0, err
+		}
+
+		args := []interface{}{key, string(encoded)}
+		switch {
+		case len(expires) > 0:
+			args = append(args, "EX", expires[0])
+		case ttl > 0:
+			args = append(args, "PX", ttl)
+		}
+
+		if err := conn.Send("MULTI"); err != nil {
+			return 0, err
+		}
+		if err := conn.Send("SET", args...); err != nil {
+			_, _ = conn.Do("DISCARD")
+			return 0, err
+		}
+
+		replies, err := redis.Values(conn.Do("EXEC"))
+		if err == redis.ErrNil {
+			// the key changed after WATCH, try again with the new count
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		for _, reply := range replies {
+			if err, ok := reply.(redis.Error); ok {
+				return 0, err
+			}
+		}
+
+		return n, nil
+	}
+}
+
+// readCounter WATCHes a counter and returns its value and remaining time to
+// live in milliseconds, 0 for a missing key or one without an expiry
+func (c *RedisCache) readCounter(conn redis.Conn, key string) (int64, int64, error) {
+	if _, err := conn.Do("WATCH", key); err != nil {
+		return 0, 0, err
+	}
+
+	ttl, err := redis.Int64(conn.Do("PTTL", key))
+	if err != nil {
+		return 0, 0, err
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	cacheEntry, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	decoded, err := decode(cacheEntry)
+	if err != nil {
+		return 0, 0, err
+	}
+	n, _ := decoded[key].(int64)
+	return n, ttl, nil
+}
+
 // Delete removes a key from the cache
 func (c *RedisCache) Delete(str string) error {
 	key := fmt.Sprintf("%s:%s", c.Prefix, str)
@@ -182,3 +304,155 @@ func (c *RedisCache) getKeys(pattern string) ([]string, error) {
 
 	return keys, nil
 }
+
+// GetMany retrieves several keys from the cache with a single MGET.
+// Keys that don't exist in the cache are left out of the returned map.
+func (c *RedisCache) GetMany(strs ...string) (map[string]interface{}, error) {
+	items := make(map[string]interface{}, len(strs))
+	if len(strs) == 0 {
+		return items, nil
+	}
+
+	args := make([]interface{}, 0, len(strs))
+	for _, str := range strs {
+		args = append(args, fmt.Sprintf("%s:%s", c.Prefix, str))
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("MGET", args...))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, cacheEntry := range values {
+		if cacheEntry == nil {
+			continue
+		}
+
+		decoded, err := decode(cacheEntry)
+		if err != nil {
+			return nil, err
+		}
+
+		items[strs[i]] = decoded[args[i].(string)]
+	}
+
+	return items, nil
+}
+
+// SetMany stores several keys in the cache. The commands are pipelined
+// inside a MULTI/EXEC block so they are sent in one round trip and applied atomically.
+func (c *RedisCache) SetMany(values map[string]interface{}, expires ...int) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	// encode everything first, so a bad value never leaves a transaction open
+	encoded := make(map[string][]byte, len(values))
+	for str, value := range values {
+		key := fmt.Sprintf("%s:%s", c.Prefix, str)
+
+		entry := Entry{}
+		entry[key] = value
+		b, err := encode(entry)
+		if err != nil {
+			return err
+		}
+		encoded[key] = b
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+
+	for key, b := range encoded {
+		var err error
+		if len(expires) > 0 {
+			err = conn.Send("SETEX", key, expires[0], string(b))
+		} else {
+			err = conn.Send("SET", key, string(b))
+		}
+		if err != nil {
+			// don't hand the connection back to the pool inside a transaction
+			_, _ = conn.Do("DISCARD")
+			return err
+		}
+	}
+
+	replies, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return err
+	}
+
+	// EXEC succeeds even when single commands fail, their errors are in the replies
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteMany removes several keys from the cache with a single DEL
+func (c *RedisCache) DeleteMany(strs ...string) error {
+	if len(strs) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(strs))
+	for _, str := range strs {
+		args = append(args, fmt.Sprintf("%s:%s", c.Prefix, str))
+	}
+
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// expiries returns when keys expire, using one pipelined PTTL per key. Keys
+// without an expiry get the zero time, missing keys the current time.
+func (c *RedisCache) expiries(strs ...string) (map[string]time.Time, error) {
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	for _, str := range strs {
+		if err := conn.Send("PTTL", fmt.Sprintf("%s:%s", c.Prefix, str)); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiries := make(map[string]time.Time, len(strs))
+	for _, str := range strs {
+		ms, err := redis.Int64(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case ms == -1: // no expiry
+			expiries[str] = time.Time{}
+		case ms < 0: // gone already
+			expiries[str] = now
+		default:
+			expiries[str] = now.Add(time.Duration(ms) * time.Millisecond)
+		}
+	}
+
+	return expiries, nil
+}
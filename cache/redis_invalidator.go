@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisInvalidator broadcasts NearCache invalidations over redis pub/sub
+type RedisInvalidator struct {
+	Conn    *redis.Pool
+	Channel string
+
+	mu     sync.Mutex
+	psc    *redis.PubSubConn
+	closed bool
+}
+
+// Publish sends an invalidation message to every subscribed instance
+func (i *RedisInvalidator) Publish(message string) error {
+	conn := i.Conn.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PUBLISH", i.Channel, message)
+	return err
+}
+
+// Subscribe listens on the channel and calls handler for every message. If the
+// subscription drops, it reconnects after a short pause until Close is called.
+func (i *RedisInvalidator) Subscribe(handler func(message string)) error {
+	for {
+		err := i.listen(handler)
+
+		i.mu.Lock()
+		closed := i.closed
+		i.mu.Unlock()
+		if closed {
+			return nil
+		}
+
+		if err != nil {
+			// while we were disconnected we may have missed invalidations,
+			// so drop everything held locally to be safe
+			handler(invalidatePrefix)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+func (i *RedisInvalidator) listen(handler func(message string)) error {
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		return nil
+	}
+	psc := &redis.PubSubConn{Conn: i.Conn.Get()}
+	i.psc = psc
+	i.mu.Unlock()
+
+	defer psc.Close()
+
+	if err := psc.Subscribe(i.Channel); err != nil {
+		return err
+	}
+
+	for {
+		// no timeout: the connection sits idle until somebody publishes
+		switch v := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			handler(string(v.Data))
+		case redis.Subscription:
+			if v.Count == 0 {
+				return errors.New("redis invalidator unsubscribed")
+			}
+		case error:
+			return v
+		}
+	}
+}
+
+// Close stops the subscription
+func (i *RedisInvalidator) Close() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.closed = true
+	if i.psc != nil {
+		return i.psc.Unsubscribe()
+	}
+	return nil
+}
@@ -32,39 +32,22 @@ func handleAuth() error {
 		exitGracefully(err)
 	}
 
-	// Copy the auth models
+	// Copy the user model. Logging in, password hashing, remember me and API
+	// tokens are handled by the framework's auth package; the app only owns
+	// the glue below.
 	err = copyFilefromTemplate("templates/models/user.go.txt", gor.RootPath+"/models/user.go") // Copy the user model
 	if err != nil {
 		exitGracefully(err)
 	}
 
-	err = copyFilefromTemplate("templates/models/token.go.txt", gor.RootPath+"/models/token.go") // Copy the token model
-	if err != nil {
-		exitGracefully(err)
-	}
-
-	err = copyFilefromTemplate("templates/models/remember-me-token.go.txt", gor.RootPath+"/models/remember_me_token.go") // Copy the remember_me_token model
-	if err != nil {
-		exitGracefully(err)
-	}
-
 	// Copy the auth middlewares
 	err = copyFilefromTemplate("templates/middleware/auth.go.txt", gor.RootPath+"/middleware/auth.go") // Copy the auth middleware
 	if err != nil {
 		exitGracefully(err)
 	}
 
-	// Copy the remember_me middleware
-	toFile := gor.RootPath + "/middleware/remember_me.go"
-	err = handleCopyDataToFile("templates/middleware/remember_me.go.txt", toFile, ReplaceDataMap{ // Copy the remember_me middleware
-		"${APP_URL}": gor.GoAppURL,
-	})
-	if err != nil {
-		return err
-	}
-
 	// Copy the auth handlers
-	toFile = gor.RootPath + "/handlers/auth-handlers.go"
+	toFile := gor.RootPath + "/handlers/auth-handlers.go"
 	err = handleCopyDataToFile("templates/handlers/auth_handlers.go.txt", toFile, ReplaceDataMap{
 		"${APP_URL}": gor.GoAppURL,
 	})
@@ -82,6 +65,26 @@ func handleAuth() error {
 		exitGracefully(err)
 	}
 
+	// Copy the account lockout notification mail templates
+	err = copyFilefromTemplate("templates/mailer/account-locked.html.tmpl", gor.RootPath+"/mail/account-locked.html.tmpl")
+	if err != nil {
+		exitGracefully(err)
+	}
+	err = copyFilefromTemplate("templates/mailer/account-locked.plain.tmpl", gor.RootPath+"/mail/account-locked.plain.tmpl")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	// Copy the email verification mail templates
+	err = copyFilefromTemplate("templates/mailer/verify-email.html.tmpl", gor.RootPath+"/mail/verify-email.html.tmpl")
+	if err != nil {
+		exitGracefully(err)
+	}
+	err = copyFilefromTemplate("templates/mailer/verify-email.plain.tmpl", gor.RootPath+"/mail/verify-email.plain.tmpl")
+	if err != nil {
+		exitGracefully(err)
+	}
+
 	// Copy the authentication views
 	err = copyFilefromTemplate("templates/views/login.jet", gor.RootPath+"/views/login.jet")
 	if err != nil {
@@ -103,13 +106,28 @@ func handleAuth() error {
 		exitGracefully(err)
 	}
 
-	color.Green("✓ Successfully created and executed the migrations for users, tokens, and remember_me_tokens.")
-	color.Green("✓ Successfully generated user and token models.")
+	err = copyFilefromTemplate("templates/views/verify-email.jet", gor.RootPath+"/views/verify-email.jet")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	err = copyFilefromTemplate("templates/views/two-factor-challenge.jet", gor.RootPath+"/views/two-factor-challenge.jet")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	color.Green("✓ Successfully created and executed the migrations for users, tokens, remember_me_tokens, two-factor authentication and social_accounts.")
+	color.Green("✓ Successfully generated the user model.")
 	color.Green("✓ Successfully created authentication middlewares.")
 	color.Yellow("")
 	color.Cyan("Note: Ensure that the models are registered in models/models.go.")
-	color.Cyan(`      - Register the User, Token, and RememberMeToken models in the models/models.go file.`)
+	color.Cyan(`      - Register the User model in the models/models.go file.`)
 	color.Cyan(`      - Also don't forget to register the generated auth middlewares in the routes.go file.`)
+	color.Cyan(`      - Route /users/verify-email to VerifyEmail, /users/verify-email/notice to VerifyEmailNotice and POST /users/verify-email/resend`)
+	color.Cyan(`        to PostResendVerification (the last two behind m.Auth), and put m.Verified on routes for verified users only.`)
+	color.Cyan(`      - API tokens are issued with app.Auth.CreateToken.`)
+	color.Cyan(`      - Set AUTH_TWO_FACTOR=true in .env and route /users/two-factor to the TwoFactorChallenge handlers to turn on 2FA.`)
+	color.Cyan(`      - For social login, set the OAUTH_* client ids in .env and route /auth/{provider} and /auth/{provider}/callback to SocialRedirect and SocialCallback.`)
 
 	return nil
 }
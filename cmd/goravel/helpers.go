@@ -24,6 +24,7 @@ func showHelp() {
 	migrate reset         - runs all down migrations in reverse order, and then all up migrations
 	make auth             - creates and runs migrations for authentication tables, and creates models and middleware
 	make session          - creates a table in the database as a session store
+	make rbac             - creates and runs migrations for roles and permissions tables (run make auth first)
 	make handler <name>   - creates a stub handler in the handlers directory
 	make model <name>     - creates a new model in the models  directory. Register all of your custom models in modes/models.go for initialization and usage
 	`)
@@ -21,6 +21,12 @@ func handleMake(arg2 string, arg3 string) error {
 			exitGracefully(err)
 		}
 
+	case "rbac":
+		err := handleRBAC()
+		if err != nil {
+			exitGracefully(err)
+		}
+
 	case "handler":
 		err := handleHandler(arg3)
 		if err != nil {
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func handleRBAC() error {
+	// create migration files
+	dbType := gor.DB.DatabaseType
+	fileName := fmt.Sprintf("%d_create_rbac_tables", time.Now().UnixMicro())
+
+	// to files:
+	upFile := gor.RootPath + "/migrations/" + fileName + "." + dbType + ".up.sql"
+	downFile := gor.RootPath + "/migrations/" + fileName + "." + dbType + ".down.sql"
+
+	err := copyFilefromTemplate("templates/migrations/rbac_tables."+dbType+".up.sql", upFile)
+	if err != nil {
+		return err
+	}
+
+	err = copyFilefromTemplate("templates/migrations/rbac_tables."+dbType+".down.sql", downFile)
+	if err != nil {
+		return err
+	}
+
+	// run those migrations
+	err = handleMigrate("up", "")
+	if err != nil {
+		exitGracefully(err)
+	}
+
+	color.Green("✓ Successfully created and executed the migrations for roles and permissions.")
+	color.Yellow("  Assign roles with app.Auth.Roles, and protect routes with m.Role and m.Permission.")
+	return nil
+}
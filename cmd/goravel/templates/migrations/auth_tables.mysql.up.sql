@@ -6,7 +6,8 @@ CREATE TABLE `users` (
     `last_name` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
     `user_active` int(11) NOT NULL,
     `email` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
-    `password` char(60) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `password` varchar(255) CHARACTER SET utf8 COLLATE utf8_unicode_ci NOT NULL,
+    `verified_at` timestamp NULL DEFAULT NULL,
     `created_at` timestamp NULL DEFAULT NULL,
     `updated_at` timestamp NULL DEFAULT NULL,
     PRIMARY KEY (`id`),
@@ -34,12 +35,56 @@ CREATE TABLE `tokens` (
     `id` int(11) NOT NULL AUTO_INCREMENT,
     `user_id` int(11) unsigned NOT NULL,
     `name` varchar(255) NOT NULL,
-    `email` varchar(255) NOT NULL,
-    `token` varchar(255) NOT NULL,
-    `token_hash` varbinary(255) DEFAULT NULL,
+    `token_hash` varchar(64) NOT NULL,
+    `abilities` text NOT NULL,
+    `last_used_at` datetime NULL DEFAULT NULL,
     `created_at` datetime NOT NULL DEFAULT current_timestamp(),
     `updated_at` datetime NOT NULL DEFAULT current_timestamp(),
     `expiry` datetime NOT NULL,
     PRIMARY KEY (`id`),
+    UNIQUE KEY `tokens_token_hash_unique` (`token_hash`),
     FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE cascade ON DELETE cascade
-) ENGINE=InnoDB AUTO_INCREMENT=30 DEFAULT CHARSET=utf8mb4;
\ No newline at end of file
+) ENGINE=InnoDB AUTO_INCREMENT=30 DEFAULT CHARSET=utf8mb4;
+
+drop table if exists two_factor_auth cascade;
+
+CREATE TABLE `two_factor_auth` (
+    `user_id` int(10) unsigned NOT NULL,
+    `secret` varchar(255) NOT NULL DEFAULT '',
+    `pending_secret` varchar(255) NOT NULL DEFAULT '',
+    `enabled` tinyint(1) NOT NULL DEFAULT 0,
+    `last_step` bigint NOT NULL DEFAULT 0,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+    PRIMARY KEY (`user_id`),
+    CONSTRAINT `two_factor_auth_user_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+drop table if exists two_factor_recovery_codes cascade;
+
+CREATE TABLE `two_factor_recovery_codes` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL,
+    `code_hash` varchar(64) NOT NULL,
+    `used_at` timestamp NULL DEFAULT NULL,
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (`id`),
+    KEY `two_factor_recovery_codes_user_id_foreign` (`user_id`),
+    CONSTRAINT `two_factor_recovery_codes_user_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+drop table if exists social_accounts cascade;
+
+CREATE TABLE `social_accounts` (
+    `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` int(10) unsigned NOT NULL,
+    `provider` varchar(50) NOT NULL,
+    `provider_user_id` varchar(255) NOT NULL,
+    `email` varchar(255) NOT NULL DEFAULT '',
+    `created_at` timestamp NOT NULL DEFAULT current_timestamp(),
+    `updated_at` timestamp NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `social_accounts_provider_unique` (`provider`, `provider_user_id`),
+    KEY `social_accounts_user_id_foreign` (`user_id`),
+    CONSTRAINT `social_accounts_user_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
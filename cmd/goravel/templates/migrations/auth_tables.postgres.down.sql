@@ -1,3 +1,8 @@
+drop table if exists social_accounts;
+
+drop table if exists two_factor_recovery_codes;
+
+drop table if exists two_factor_auth;
 
 drop table if exists users cascade;
 
@@ -14,7 +14,8 @@ CREATE TABLE users (
     last_name character varying(255) NOT NULL,
     user_active integer NOT NULL DEFAULT 0,
     email character varying(255) NOT NULL UNIQUE,
-    password character varying(60) NOT NULL,
+    password character varying(255) NOT NULL,
+    verified_at timestamp without time zone NULL,
     created_at timestamp without time zone NOT NULL DEFAULT now(),
     updated_at timestamp without time zone NOT NULL DEFAULT now()
 );
@@ -44,10 +45,10 @@ drop table if exists tokens;
 CREATE TABLE tokens (
     id SERIAL PRIMARY KEY,
     user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
-    first_name character varying(255) NOT NULL,
-    email character varying(255) NOT NULL,
-    token character varying(255) NOT NULL,
-    token_hash bytea NOT NULL,
+    name character varying(255) NOT NULL,
+    token_hash character varying(64) NOT NULL UNIQUE,
+    abilities text NOT NULL DEFAULT '',
+    last_used_at timestamp without time zone NULL,
     created_at timestamp without time zone NOT NULL DEFAULT now(),
     updated_at timestamp without time zone NOT NULL DEFAULT now(),
     expiry timestamp without time zone NOT NULL
@@ -56,4 +57,53 @@ CREATE TABLE tokens (
 CREATE TRIGGER set_timestamp
     BEFORE UPDATE ON tokens
     FOR EACH ROW
-    EXECUTE PROCEDURE trigger_set_timestamp();
\ No newline at end of file
+    EXECUTE PROCEDURE trigger_set_timestamp();
+
+drop table if exists two_factor_auth;
+
+CREATE TABLE two_factor_auth (
+    user_id integer PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    secret character varying(255) NOT NULL DEFAULT '',
+    pending_secret character varying(255) NOT NULL DEFAULT '',
+    enabled boolean NOT NULL DEFAULT false,
+    last_step bigint NOT NULL DEFAULT 0,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    updated_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON two_factor_auth
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
+
+drop table if exists two_factor_recovery_codes;
+
+CREATE TABLE two_factor_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    code_hash character varying(64) NOT NULL,
+    used_at timestamp without time zone NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX two_factor_recovery_codes_user_id_idx ON two_factor_recovery_codes (user_id);
+
+drop table if exists social_accounts;
+
+CREATE TABLE social_accounts (
+    id SERIAL PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    provider character varying(50) NOT NULL,
+    provider_user_id character varying(255) NOT NULL,
+    email character varying(255) NOT NULL DEFAULT '',
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    updated_at timestamp without time zone NOT NULL DEFAULT now(),
+    UNIQUE (provider, provider_user_id)
+);
+
+CREATE INDEX social_accounts_user_id_idx ON social_accounts (user_id);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON social_accounts
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
@@ -0,0 +1,7 @@
+drop table if exists permission_role;
+
+drop table if exists role_user;
+
+drop table if exists permissions cascade;
+
+drop table if exists roles cascade;
@@ -0,0 +1,49 @@
+drop table if exists roles cascade;
+
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name character varying(100) NOT NULL UNIQUE,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    updated_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON roles
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
+
+drop table if exists permissions cascade;
+
+CREATE TABLE permissions (
+    id SERIAL PRIMARY KEY,
+    name character varying(100) NOT NULL UNIQUE,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    updated_at timestamp without time zone NOT NULL DEFAULT now()
+);
+
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON permissions
+    FOR EACH ROW
+    EXECUTE PROCEDURE trigger_set_timestamp();
+
+drop table if exists role_user;
+
+CREATE TABLE role_user (
+    user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    role_id integer NOT NULL REFERENCES roles(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX role_user_role_id_idx ON role_user (role_id);
+
+drop table if exists permission_role;
+
+CREATE TABLE permission_role (
+    permission_id integer NOT NULL REFERENCES permissions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    role_id integer NOT NULL REFERENCES roles(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (permission_id, role_id)
+);
+
+CREATE INDEX permission_role_role_id_idx ON permission_role (role_id);
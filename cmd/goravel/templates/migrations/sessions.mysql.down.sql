@@ -1 +1,3 @@
+drop table if exists session_index;
+
 drop table sessions;
\ No newline at end of file
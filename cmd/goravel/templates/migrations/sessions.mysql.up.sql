@@ -4,4 +4,15 @@ CREATE TABLE sessions (
 	expiry TIMESTAMP(6) NOT NULL
 );
 
-CREATE INDEX sessions_expiry_idx ON sessions (expiry);
\ No newline at end of file
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+
+CREATE TABLE session_index (
+	token CHAR(43) PRIMARY KEY,
+	user_id INT UNSIGNED NOT NULL,
+	user_agent VARCHAR(512) NOT NULL DEFAULT '',
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	last_seen TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+);
+
+CREATE INDEX session_index_user_id_idx ON session_index (user_id);
@@ -4,4 +4,15 @@ CREATE TABLE sessions (
 	expiry TIMESTAMPTZ NOT NULL
 );
 
-CREATE INDEX sessions_expiry_idx ON sessions (expiry);
\ No newline at end of file
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
+
+CREATE TABLE session_index (
+	token TEXT PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_address VARCHAR(64) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_seen TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX session_index_user_id_idx ON session_index (user_id);
@@ -0,0 +1,33 @@
+package goravel
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+)
+
+// files returns the file system holding the app's views, mail, migrations
+// and public folders: FS if set, the RootPath folder otherwise
+func (g *Goravel) files() fs.FS {
+	if g.FS != nil {
+		return g.FS
+	}
+	return os.DirFS(g.RootPath)
+}
+
+// folder returns one of the app's folders, e.g. "views", as a file system
+func (g *Goravel) folder(name string) fs.FS {
+	sub, err := fs.Sub(g.files(), name)
+	if err != nil {
+		// only happens for invalid names, which the callers don't pass
+		panic(err)
+	}
+	return sub
+}
+
+// Public serves the files in the public folder, e.g.
+//
+//	app.Routes.Handle("/public/*", http.StripPrefix("/public", app.Public()))
+func (g *Goravel) Public() http.Handler {
+	return http.FileServer(http.FS(g.folder("public")))
+}
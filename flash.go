@@ -0,0 +1,43 @@
+package goravel
+
+import (
+	"net/http"
+
+	"github.com/saalikmubeen/goravel/render"
+)
+
+// Flash queues a message for the next rendered page. level is one of
+// render.FlashSuccess, render.FlashInfo, render.FlashWarning or render.FlashError.
+func (g *Goravel) Flash(r *http.Request, level, message string) {
+	g.Render.Flash(r.Context(), level, message)
+}
+
+// FlashSuccess queues a success message for the next rendered page
+func (g *Goravel) FlashSuccess(r *http.Request, message string) {
+	g.Flash(r, render.FlashSuccess, message)
+}
+
+// FlashInfo queues an informational message for the next rendered page
+func (g *Goravel) FlashInfo(r *http.Request, message string) {
+	g.Flash(r, render.FlashInfo, message)
+}
+
+// FlashWarning queues a warning for the next rendered page
+func (g *Goravel) FlashWarning(r *http.Request, message string) {
+	g.Flash(r, render.FlashWarning, message)
+}
+
+// FlashError queues an error message for the next rendered page
+func (g *Goravel) FlashError(r *http.Request, message string) {
+	g.Flash(r, render.FlashError, message)
+}
+
+// RedirectWithErrors flashes the submitted form values and the validation
+// errors and redirects to url, typically the page showing the form. The
+// rendered form can then use .Old and .FieldError on the template data to
+// show what the user typed and what was wrong with it.
+func (g *Goravel) RedirectWithErrors(w http.ResponseWriter, r *http.Request, url string, v *Validation) {
+	g.Render.FlashInput(r.Context(), v.Data)
+	g.Render.FlashErrors(r.Context(), v.Errors)
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
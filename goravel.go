@@ -1,7 +1,9 @@
 package goravel
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"strconv"
@@ -15,8 +17,11 @@ import (
 	"github.com/gomodule/redigo/redis"
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
+	"github.com/saalikmubeen/goravel/auth"
 	"github.com/saalikmubeen/goravel/cache"
+	"github.com/saalikmubeen/goravel/jwt"
 	"github.com/saalikmubeen/goravel/mailer"
+	"github.com/saalikmubeen/goravel/oauth"
 	"github.com/saalikmubeen/goravel/render"
 	"github.com/saalikmubeen/goravel/session"
 )
@@ -43,6 +48,8 @@ var redisPool *redis.Pool
 var myBadgerCache *cache.BadgerCache
 var badgerConn *badger.DB
 
+var myNearCache *cache.NearCache
+
 type Goravel struct {
 	AppName       string
 	GoAppURL      string
@@ -52,6 +59,7 @@ type Goravel struct {
 	ErrorLog      *log.Logger
 	InfoLog       *log.Logger
 	RootPath      string // rootPath is the path that we are in when we start the goravel app
+	FS            fs.FS  // views, mail, migrations and public folders, e.g. an embed.FS set before New; read from RootPath if nil
 	Render        *render.Render
 	Routes        *chi.Mux
 	JetViews      *jet.Set
@@ -61,10 +69,15 @@ type Goravel struct {
 	EncryptionKey string
 	Mail          mailer.Mail
 	Scheduler     *cron.Cron
+	Auth          *auth.Auth
+	JWT           *jwt.Manager // nil when neither JWT_ALG nor KEY is set
+	OAuth         *oauth.OAuth
 
 	// not exported, used internally
 	// contains mostly loaded environment variables.
 	config config
+
+	sessionIndex session.Index // maps users to their sessions, nil if the store can't be indexed
 }
 
 type config struct {
@@ -74,6 +87,7 @@ type config struct {
 	sessionType string
 	database    databaseConfig
 	redis       redisConfig
+	badger      badgerConfig
 }
 
 // CreateFolderStructure creates necessary folders for our Goravel application
@@ -116,6 +130,8 @@ func (g *Goravel) createRenderer() {
 		Port:     g.config.port,
 		JetViews: g.JetViews,
 		Session:  g.Session,
+		Can:      g.Can,
+		Debug:    g.Debug,
 	}
 
 	g.Render = &myRenderer
@@ -144,45 +160,23 @@ func (g *Goravel) BuildDSN() string {
 	return dsn
 }
 
-func (g *Goravel) createRedisPool() *redis.Pool {
-	return &redis.Pool{
-		MaxIdle:     50,
-		MaxActive:   10000,
-		IdleTimeout: 240 * time.Second,
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp",
-				g.config.redis.host,
-				redis.DialPassword(g.config.redis.password))
-		},
-
-		TestOnBorrow: func(conn redis.Conn, t time.Time) error {
-			_, err := conn.Do("PING")
-			return err
-		},
-	}
-}
-
-func (g *Goravel) createRedisCache() *cache.RedisCache {
-	cacheClient := cache.RedisCache{
-		Conn:   g.createRedisPool(),
-		Prefix: g.config.redis.prefix,
-	}
-	return &cacheClient
-}
-
-func (g *Goravel) createBadgerConn() *badger.DB {
-	db, err := badger.Open(badger.DefaultOptions(g.RootPath + "/tmp/badger"))
-	if err != nil {
-		return nil
+// createNearCache wraps backend in a local LRU. When a redis pool is available,
+// invalidations are broadcast over it so other app instances evict their copies too.
+func (g *Goravel) createNearCache(backend cache.Cache) *cache.NearCache {
+	var invalidator cache.Invalidator
+	if redisPool != nil {
+		invalidator = &cache.RedisInvalidator{
+			Conn:    redisPool,
+			Channel: g.config.redis.keyPrefix() + ":cache-invalidations",
+		}
 	}
-	return db
-}
 
-func (g *Goravel) createBadgerCache() *cache.BadgerCache {
-	cacheClient := cache.BadgerCache{
-		Conn: g.createBadgerConn(),
-	}
-	return &cacheClient
+	return cache.NewNearCache(
+		backend,
+		envInt("CACHE_NEAR_SIZE", 1000),
+		envSeconds("CACHE_NEAR_TTL", 60*time.Second),
+		invalidator,
+	)
 }
 
 func (g *Goravel) createMailer() mailer.Mail {
@@ -190,6 +184,7 @@ func (g *Goravel) createMailer() mailer.Mail {
 	m := mailer.Mail{
 		Domain:      os.Getenv("MAIL_DOMAIN"),
 		Templates:   g.RootPath + "/mail",
+		TemplatesFS: g.folder("mail"),
 		Host:        os.Getenv("SMTP_HOST"),
 		Port:        port,
 		Username:    os.Getenv("SMTP_USERNAME"),
@@ -248,22 +243,24 @@ func (g *Goravel) New(rootPath string) error {
 		port:     os.Getenv("PORT"),
 		renderer: os.Getenv("RENDERER"),
 		cookie: cookieConfig{
-			name:     os.Getenv("COOKIE_NAME"),
-			lifetime: os.Getenv("COOKIE_LIFETIME"),
-			persist:  os.Getenv("COOKIE_PERSISTS"),
-			secure:   os.Getenv("COOKIE_SECURE"),
-			domain:   os.Getenv("COOKIE_DOMAIN"),
+			name:        os.Getenv("COOKIE_NAME"),
+			lifetime:    os.Getenv("COOKIE_LIFETIME"),
+			idleTimeout: os.Getenv("COOKIE_IDLE_TIMEOUT"),
+			persist:     os.Getenv("COOKIE_PERSISTS"),
+			secure:      os.Getenv("COOKIE_SECURE"),
+			domain:      os.Getenv("COOKIE_DOMAIN"),
+			path:        os.Getenv("COOKIE_PATH"),
+			sameSite:    os.Getenv("COOKIE_SAMESITE"),
+			httpOnly:    os.Getenv("COOKIE_HTTP_ONLY"),
+			hostPrefix:  os.Getenv("COOKIE_HOST_PREFIX"),
 		},
 		database: databaseConfig{
 			dsn:          g.BuildDSN(),
 			databaseType: os.Getenv("DATABASE_TYPE"),
 		},
 		sessionType: os.Getenv("SESSION_TYPE"),
-		redis: redisConfig{
-			host:     os.Getenv("REDIS_HOST"),
-			password: os.Getenv("REDIS_PASSWORD"),
-			prefix:   os.Getenv("REDIS_PREFIX"),
-		},
+		redis:       loadRedisConfig(),
+		badger:      loadBadgerConfig(),
 	}
 
 	secure := false
@@ -306,70 +303,103 @@ func (g *Goravel) New(rootPath string) error {
 
 	// ** Initilize the cache
 	if os.Getenv("CACHE") == "redis" || os.Getenv("SESSION_TYPE") == "redis" {
-		myRedisCache = g.createRedisCache()
+		myRedisCache, err = g.createRedisCache()
+		if err != nil {
+			return err
+		}
 		redisPool = myRedisCache.Conn
 		g.Cache = myRedisCache
 	}
 
-	if os.Getenv("CACHE") == "badger" {
-		myBadgerCache = g.createBadgerCache()
-		g.Cache = myBadgerCache
+	if os.Getenv("CACHE") == "badger" || os.Getenv("SESSION_TYPE") == "badger" {
+		myBadgerCache, err = g.createBadgerCache()
+		if err != nil {
+			return err
+		}
 		badgerConn = myBadgerCache.Conn
 
+		// the badger database may only be open for the session store
+		if os.Getenv("CACHE") == "badger" {
+			g.Cache = myBadgerCache
+		}
+
 		// Run the garbage collector on the badger database
-		// every 24 hours
-		_, err = g.Scheduler.AddFunc("@daily", func() {
-			_ = myBadgerCache.Conn.RunValueLogGC(0.7)
-		})
+		// on the configured schedule (every 24 hours by default)
+		err = g.scheduleBadgerGC(badgerConn)
 		if err != nil {
 			return err
 		}
 	}
 
+	// ** Put the in-process near cache in front of the configured cache
+	if g.Cache != nil && envBool("CACHE_NEAR", false) {
+		myNearCache = g.createNearCache(g.Cache)
+		g.Cache = myNearCache
+	}
+
 	// ** Create and initialize the session
 	session := session.Session{
-		CookieLifetime: g.config.cookie.lifetime,
-		CookiePersist:  g.config.cookie.persist,
-		CookieName:     g.config.cookie.name,
-		CookieDomain:   g.config.cookie.domain,
-		CookieSecure:   g.config.cookie.secure,
-		SessionType:    g.config.sessionType,
+		CookieLifetime:    g.config.cookie.lifetime,
+		CookieIdleTimeout: g.config.cookie.idleTimeout,
+		CookiePersist:     g.config.cookie.persist,
+		CookieName:        g.config.cookie.name,
+		CookieDomain:      g.config.cookie.domain,
+		CookieSecure:      g.config.cookie.secure,
+		CookiePath:        g.config.cookie.path,
+		CookieSameSite:    g.config.cookie.sameSite,
+		CookieHTTPOnly:    g.config.cookie.httpOnly,
+		CookieHostPrefix:  g.config.cookie.hostPrefix,
+		SessionType:       g.config.sessionType,
+		CookieKeys:        append([]string{g.EncryptionKey}, envList("SESSION_OLD_KEYS")...),
 	}
 
 	// set the session store
 	switch g.config.sessionType {
 	case "redis":
-		session.RedisPool = myRedisCache.Conn
+		session.RedisPool = redisPool
+		if g.config.redis.mode == "cluster" {
+			session.RedisPrefix = g.config.redis.keyPrefix() + ":session:"
+		}
 	case "mysql", "postgres", "postgresql", "mariadb":
 		session.DBPool = g.DB.Pool
+	case "badger":
+		session.BadgerConn = badgerConn
+	case "cache":
+		if g.Cache == nil {
+			return errors.New("SESSION_TYPE=cache requires CACHE to be set")
+		}
+		session.Cache = g.Cache
+	}
+
+	g.Session, err = session.InitSession()
+	if err != nil {
+		return err
+	}
+	g.sessionIndex = g.createSessionIndex()
+	g.Auth, err = g.createAuth()
+	if err != nil {
+		return err
 	}
+	g.OAuth = g.createOAuth()
 
-	g.Session = session.InitSession()
+	g.JWT, err = g.createJWT()
+	if err != nil {
+		return err
+	}
 
 	//**  create the routes
 	// Routes have to be created after the session has been initialized
 	// because the session is used in the routes
 	g.Routes = g.initRoutes().(*chi.Mux)
 
-	// ** Initialize and create the Jet views
-	if g.Debug {
-		var views = jet.NewSet(
-			jet.NewOSFileSystemLoader(fmt.Sprintf("%s/views", rootPath)),
-			jet.InDevelopmentMode(),
-		)
-
-		g.JetViews = views
-	} else {
-		var views = jet.NewSet(
-			jet.NewOSFileSystemLoader(fmt.Sprintf("%s/views", rootPath)),
-		)
-		g.JetViews = views
-	}
-
 	// ** create the renderer that renders our templates
-	// The renderer has to be created after the Jet views and session is initialized
-	// because the renderer uses the Jet views and session
+	// The renderer has to be created after the session is initialized
+	// because the renderer uses the session
 	g.createRenderer()
+	if err := g.Render.Load(g.folder("views")); err != nil {
+		return err
+	}
+	g.JetViews = g.Render.JetViews
 
 	// ** Start the mail listener
 	go g.Mail.ListenForMail()
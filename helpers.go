@@ -7,6 +7,9 @@ import (
 	"encoding/base64"
 	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // CreateDirIfNotExists creates a new directory if it does not exist
@@ -111,3 +114,42 @@ func (e *Encryption) Decrypt(cryptoText string) (string, error) {
 
 	return string(ciphertext), nil
 }
+
+// envInt reads an integer environment variable, returning def if it is unset or invalid
+func envInt(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+// envBool reads a boolean environment variable, returning def if it is unset or invalid
+func envBool(key string, def bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// envSeconds reads an environment variable holding a number of seconds and
+// returns it as a time.Duration, returning def if it is unset or invalid
+func envSeconds(key string, def time.Duration) time.Duration {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return time.Duration(i) * time.Second
+}
+
+// envList reads a comma separated environment variable into a slice, dropping empty items
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
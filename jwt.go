@@ -0,0 +1,32 @@
+package goravel
+
+import (
+	"os"
+	"time"
+
+	"github.com/saalikmubeen/goravel/jwt"
+)
+
+// createJWT sets up JWT issuing and verification. HS256 tokens are signed
+// with KEY; RS256 and EdDSA need JWT_PRIVATE_KEY and/or JWT_PUBLIC_KEY
+// pointing at PEM files. Revoked tokens are kept in the configured cache.
+// It returns nil when neither JWT_ALG nor KEY is set.
+func (g *Goravel) createJWT() (*jwt.Manager, error) {
+	alg := os.Getenv("JWT_ALG")
+	if alg == "" && g.EncryptionKey == "" {
+		return nil, nil
+	}
+
+	signer, err := jwt.NewSigner(alg, []byte(g.EncryptionKey), os.Getenv("JWT_PRIVATE_KEY"), os.Getenv("JWT_PUBLIC_KEY"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwt.Manager{
+		Signer:     signer,
+		Issuer:     os.Getenv("JWT_ISSUER"),
+		AccessTTL:  envSeconds("JWT_ACCESS_TTL", 15*time.Minute),
+		RefreshTTL: envSeconds("JWT_REFRESH_TTL", 30*24*time.Hour),
+		Cache:      g.Cache,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformed is returned for strings that aren't a JWT
+	ErrMalformed = errors.New("jwt: malformed token")
+	// ErrInvalidSignature is returned when a token wasn't signed by our key
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	// ErrAlgorithm is returned when a token names a different algorithm than
+	// the one we sign with; accepting it would allow "alg: none" style forgeries
+	ErrAlgorithm = errors.New("jwt: unexpected algorithm")
+	// ErrExpired is returned for tokens past their expiry
+	ErrExpired = errors.New("jwt: token has expired")
+	// ErrNotYetValid is returned for tokens used before their "nbf" time
+	ErrNotYetValid = errors.New("jwt: token is not valid yet")
+	// ErrInvalidIssuer is returned when a token was issued by someone else
+	ErrInvalidIssuer = errors.New("jwt: invalid issuer")
+	// ErrRevoked is returned for tokens on the revocation list
+	ErrRevoked = errors.New("jwt: token has been revoked")
+	// ErrWrongType is returned when an access token is used as a refresh token or vice versa
+	ErrWrongType = errors.New("jwt: wrong token type")
+)
+
+// Token types
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+// Claims is the payload of a token
+type Claims struct {
+	Subject   string `json:"sub,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	ID        string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	Type      string `json:"typ,omitempty"` // TypeAccess or TypeRefresh
+	// Family links the refresh tokens created from one login, so that a
+	// reused refresh token can revoke all of them
+	Family string `json:"fam,omitempty"`
+	// Data holds application claims, e.g. a user's roles
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// ExpiresIn returns how long the token is still valid for
+func (c *Claims) ExpiresIn() time.Duration {
+	return time.Until(time.Unix(c.ExpiresAt, 0))
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Encode signs claims into a compact JWT
+func Encode(s Signer, claims *Claims) (string, error) {
+	h, err := json.Marshal(header{Alg: s.Alg(), Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	p, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := encoding.EncodeToString(h) + "." + encoding.EncodeToString(p)
+
+	sig, err := s.Sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + encoding.EncodeToString(sig), nil
+}
+
+// Decode checks a token's signature and time claims and returns its claims
+func Decode(s Signer, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	h, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var head header
+	if err := json.Unmarshal(h, &head); err != nil {
+		return nil, ErrMalformed
+	}
+	if head.Alg != s.Alg() {
+		return nil, ErrAlgorithm
+	}
+
+	sig, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if err := s.Verify([]byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	p, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	var claims Claims
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, ErrMalformed
+	}
+
+	now := time.Now().Unix()
+	if claims.ExpiresAt != 0 && now >= claims.ExpiresAt {
+		return nil, ErrExpired
+	}
+	if claims.NotBefore != 0 && now < claims.NotBefore {
+		return nil, ErrNotYetValid
+	}
+
+	return &claims, nil
+}
+
+// newID returns a random token ID
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,87 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeRejectsOtherAlgorithms(t *testing.T) {
+	hs := &HS256{Secret: []byte("secret")}
+	token, err := Encode(hs, &Claims{Subject: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(hs, token); err != nil {
+		t.Fatalf("Decode = %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	for alg, header := range map[string]string{
+		"none":  `{"alg":"none","typ":"JWT"}`,
+		"HS512": `{"alg":"HS512","typ":"JWT"}`,
+		"RS256": `{"alg":"RS256","typ":"JWT"}`,
+	} {
+		forged := encoding.EncodeToString([]byte(header)) + "." + parts[1] + "."
+		if alg != "none" {
+			forged += parts[2]
+		}
+		if _, err := Decode(hs, forged); !errors.Is(err, ErrAlgorithm) {
+			t.Errorf("Decode(alg %s) = %v, want ErrAlgorithm", alg, err)
+		}
+	}
+
+	// a public key used as an HMAC secret must not verify either
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed := &EdDSA{PrivateKey: private, PublicKey: public}
+	forged, err := Encode(&HS256{Secret: public}, &Claims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(ed, forged); !errors.Is(err, ErrAlgorithm) {
+		t.Fatalf("Decode(HS256 token with EdDSA signer) = %v, want ErrAlgorithm", err)
+	}
+}
+
+func TestDecodeChecksSignatureAndTimes(t *testing.T) {
+	hs := &HS256{Secret: []byte("secret")}
+	now := time.Now()
+
+	token, err := Encode(hs, &Claims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	payload, _ := json.Marshal(&Claims{Subject: "2"})
+	tampered := parts[0] + "." + encoding.EncodeToString(payload) + "." + parts[2]
+	if _, err := Decode(hs, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Decode(tampered payload) = %v, want ErrInvalidSignature", err)
+	}
+	if _, err := Decode(&HS256{Secret: []byte("other")}, token); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Decode(other secret) = %v, want ErrInvalidSignature", err)
+	}
+
+	for want, claims := range map[error]*Claims{
+		ErrExpired:     {ExpiresAt: now.Add(-time.Second).Unix()},
+		ErrNotYetValid: {NotBefore: now.Add(time.Minute).Unix()},
+	} {
+		token, err := Encode(hs, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(hs, token); !errors.Is(err, want) {
+			t.Errorf("Decode = %v, want %v", err, want)
+		}
+	}
+
+	if _, err := Decode(hs, "not.a.token"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decode(garbage) = %v, want ErrMalformed", err)
+	}
+}
@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+// revocation list keys in the cache
+const (
+	revokedPrefix       = "jwt:revoked:"
+	revokedFamilyPrefix = "jwt:revoked-family:"
+)
+
+// Pair is what a client gets on login and on refresh
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
+
+// Manager issues, refreshes, verifies and revokes access/refresh token pairs
+type Manager struct {
+	Signer     Signer
+	Issuer     string        // checked on verify when set
+	AccessTTL  time.Duration // 15 minutes if not set
+	RefreshTTL time.Duration // 30 days if not set
+	// Cache keeps the revocation list. Without it tokens can't be revoked and
+	// refresh tokens aren't rotated, only re-issued.
+	Cache cache.Cache
+}
+
+// Issue creates a new token pair for subject (usually the user ID), e.g. after login
+func (m *Manager) Issue(subject string, data map[string]interface{}) (*Pair, error) {
+	family, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return m.issue(subject, family, data)
+}
+
+// Verify checks an access token and returns its claims
+func (m *Manager) Verify(token string) (*Claims, error) {
+	claims, err := m.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != TypeAccess {
+		return nil, ErrWrongType
+	}
+	return claims, nil
+}
+
+// Refresh trades a refresh token for a new pair. The old refresh token is
+// revoked (rotation); if it is ever presented again, it was most likely
+// stolen, so every token of its family is revoked and the user has to log in again.
+func (m *Manager) Refresh(refreshToken string) (*Pair, error) {
+	claims, err := m.verify(refreshToken)
+	if errors.Is(err, ErrRevoked) && claims != nil {
+		_ = m.revokeFamily(claims)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.Type != TypeRefresh {
+		return nil, ErrWrongType
+	}
+
+	if m.Cache != nil {
+		// revoking is also the claim on the token: of two concurrent refreshes
+		// only one can add the key, the other one is reuse
+		claimed, err := m.Cache.Add(revokedPrefix+claims.ID, true, ttlSeconds(claims.ExpiresIn()))
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			_ = m.revokeFamily(claims)
+			return nil, ErrRevoked
+		}
+	}
+
+	return m.issue(claims.Subject, claims.Family, claims.Data)
+}
+
+// Revoke puts a token on the revocation list until it expires, e.g. on logout
+func (m *Manager) Revoke(claims *Claims) error {
+	if m.Cache == nil {
+		return errors.New("jwt: revoking tokens needs a cache")
+	}
+	return m.Cache.Set(revokedPrefix+claims.ID, true, ttlSeconds(claims.ExpiresIn()))
+}
+
+// RevokeToken puts a raw access or refresh token on the revocation list
+func (m *Manager) RevokeToken(token string) error {
+	claims, err := m.verify(token)
+	if err != nil {
+		return err
+	}
+	return m.Revoke(claims)
+}
+
+// Middleware only lets requests with a valid access token in their
+// "Authorization: Bearer" header through, putting its claims in the request
+// context for ClaimsFromContext.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			unauthorized(w, "missing bearer token")
+			return
+		}
+
+		claims, err := m.Verify(strings.TrimSpace(token))
+		if err != nil {
+			unauthorized(w, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims)))
+	})
+}
+
+type contextKey string
+
+const claimsContextKey contextKey = "jwt.claims"
+
+// ClaimsFromContext returns the claims put in the request context by Middleware
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*Claims)
+	return claims, ok
+}
+
+func (m *Manager) issue(subject, family string, data map[string]interface{}) (*Pair, error) {
+	now := time.Now()
+
+	access, err := m.sign(&Claims{
+		Subject:   subject,
+		Issuer:    m.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(m.accessTTL()).Unix(),
+		Type:      TypeAccess,
+		Family:    family,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := m.sign(&Claims{
+		Subject:   subject,
+		Issuer:    m.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(m.refreshTTL()).Unix(),
+		Type:      TypeRefresh,
+		Family:    family,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(m.accessTTL().Seconds()),
+	}, nil
+}
+
+func (m *Manager) sign(claims *Claims) (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	claims.ID = id
+	return Encode(m.Signer, claims)
+}
+
+// verify decodes a token of either type and checks the issuer and the
+// revocation list. The claims of a revoked token are returned along with ErrRevoked.
+func (m *Manager) verify(token string) (*Claims, error) {
+	claims, err := Decode(m.Signer, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Issuer != "" && claims.Issuer != m.Issuer {
+		return nil, ErrInvalidIssuer
+	}
+
+	if m.Cache != nil {
+		revoked, err := m.Cache.GetMany(revokedPrefix+claims.ID, revokedFamilyPrefix+claims.Family)
+		if err != nil {
+			return nil, err
+		}
+		if len(revoked) > 0 {
+			return claims, ErrRevoked
+		}
+	}
+
+	return claims, nil
+}
+
+// revokeFamily revokes every token issued from the same login as claims
+func (m *Manager) revokeFamily(claims *Claims) error {
+	if m.Cache == nil || claims.Family == "" {
+		return nil
+	}
+	return m.Cache.Set(revokedFamilyPrefix+claims.Family, true, ttlSeconds(m.refreshTTL()))
+}
+
+func (m *Manager) accessTTL() time.Duration {
+	if m.AccessTTL == 0 {
+		return 15 * time.Minute
+	}
+	return m.AccessTTL
+}
+
+func (m *Manager) refreshTTL() time.Duration {
+	if m.RefreshTTL == 0 {
+		return 30 * 24 * time.Hour
+	}
+	return m.RefreshTTL
+}
+
+// ttlSeconds rounds a duration up to whole seconds, at least one
+func ttlSeconds(d time.Duration) int {
+	s := int((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   true,
+		"message": message,
+	})
+}
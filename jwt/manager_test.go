@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLoggingLevel(badger.ERROR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &Manager{
+		Signer: &HS256{Secret: []byte("secret")},
+		Issuer: "goravel",
+		Cache:  &cache.BadgerCache{Conn: db},
+	}
+}
+
+func TestManagerTokenTypes(t *testing.T) {
+	m := newTestManager(t)
+	pair, err := m.Issue("1", map[string]interface{}{"role": "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := m.Verify(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "1" || claims.Data["role"] != "admin" {
+		t.Fatalf("Verify = %+v", claims)
+	}
+
+	if _, err := m.Verify(pair.RefreshToken); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Verify(refresh token) = %v, want ErrWrongType", err)
+	}
+	if _, err := m.Refresh(pair.AccessToken); !errors.Is(err, ErrWrongType) {
+		t.Errorf("Refresh(access token) = %v, want ErrWrongType", err)
+	}
+
+	other := &Manager{Signer: m.Signer, Issuer: "someone else"}
+	foreign, err := other.Issue("1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(foreign.AccessToken); !errors.Is(err, ErrInvalidIssuer) {
+		t.Errorf("Verify(other issuer) = %v, want ErrInvalidIssuer", err)
+	}
+}
+
+func TestManagerRefreshRotation(t *testing.T) {
+	m := newTestManager(t)
+	first, err := m.Issue("1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := m.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(second.AccessToken); err != nil {
+		t.Fatalf("Verify(rotated access token) = %v", err)
+	}
+
+	// the first refresh token was rotated out; presenting it again means it
+	// leaked, which revokes the whole family
+	if _, err := m.Refresh(first.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("Refresh(reused token) = %v, want ErrRevoked", err)
+	}
+	if _, err := m.Verify(second.AccessToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Verify(access token of a revoked family) = %v, want ErrRevoked", err)
+	}
+	if _, err := m.Refresh(second.RefreshToken); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Refresh(refresh token of a revoked family) = %v, want ErrRevoked", err)
+	}
+
+	// other logins aren't affected
+	other, err := m.Issue("1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("Refresh(other family) = %v", err)
+	}
+}
+
+func TestManagerConcurrentRefresh(t *testing.T) {
+	m := newTestManager(t)
+	pair, err := m.Issue("1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		refreshed int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Refresh(pair.RefreshToken); err == nil {
+				mu.Lock()
+				refreshed++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrRevoked) {
+				t.Errorf("Refresh = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if refreshed != 1 {
+		t.Fatalf("one refresh token was traded %d times", refreshed)
+	}
+}
+
+func TestManagerRevoke(t *testing.T) {
+	m := newTestManager(t)
+	pair, err := m.Issue("1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.RevokeToken(pair.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(pair.AccessToken); !errors.Is(err, ErrRevoked) {
+		t.Fatalf("Verify(revoked token) = %v, want ErrRevoked", err)
+	}
+
+	if err := (&Manager{Signer: m.Signer}).Revoke(&Claims{ID: "x"}); err == nil {
+		t.Fatal("Revoke without a cache succeeded")
+	}
+}
@@ -0,0 +1,200 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Signer signs and verifies the "header.payload" part of a token with one algorithm
+type Signer interface {
+	// Alg is the algorithm's name in the token header, e.g. "HS256"
+	Alg() string
+	Sign(data []byte) ([]byte, error)
+	Verify(data, signature []byte) error
+}
+
+// HS256 signs with HMAC-SHA256 and a shared secret
+type HS256 struct {
+	Secret []byte
+}
+
+func (s *HS256) Alg() string { return "HS256" }
+
+func (s *HS256) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (s *HS256) Verify(data, signature []byte) error {
+	expected, _ := s.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// RS256 signs with RSASSA-PKCS1-v1_5 and SHA-256. A verify-only signer
+// (e.g. in a service that only checks tokens) needs just the PublicKey.
+type RS256 struct {
+	PrivateKey *rsa.PrivateKey
+	PublicKey  *rsa.PublicKey
+}
+
+func (s *RS256) Alg() string { return "RS256" }
+
+func (s *RS256) Sign(data []byte) ([]byte, error) {
+	if s.PrivateKey == nil {
+		return nil, errors.New("jwt: RS256 signer has no private key")
+	}
+	sum := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, s.PrivateKey, crypto.SHA256, sum[:])
+}
+
+func (s *RS256) Verify(data, signature []byte) error {
+	sum := sha256.Sum256(data)
+	if rsa.VerifyPKCS1v15(s.PublicKey, crypto.SHA256, sum[:], signature) != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// EdDSA signs with Ed25519. A verify-only signer needs just the PublicKey.
+type EdDSA struct {
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+func (s *EdDSA) Alg() string { return "EdDSA" }
+
+func (s *EdDSA) Sign(data []byte) ([]byte, error) {
+	if s.PrivateKey == nil {
+		return nil, errors.New("jwt: EdDSA signer has no private key")
+	}
+	return ed25519.Sign(s.PrivateKey, data), nil
+}
+
+func (s *EdDSA) Verify(data, signature []byte) error {
+	if !ed25519.Verify(s.PublicKey, data, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// NewSigner creates the signer for alg. HS256 uses secret; RS256 and EdDSA
+// read PEM encoded keys from privateKeyFile (PKCS #8, or PKCS #1 for RSA)
+// and publicKeyFile (PKIX). Either key file may be empty, as long as the
+// other one is given: without a private key tokens can only be verified.
+func NewSigner(alg string, secret []byte, privateKeyFile, publicKeyFile string) (Signer, error) {
+	switch alg {
+	case "", "HS256":
+		if len(secret) == 0 {
+			return nil, errors.New("jwt: HS256 needs a secret")
+		}
+		return &HS256{Secret: secret}, nil
+	case "RS256", "EdDSA":
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", alg)
+	}
+
+	var private, public interface{}
+	var err error
+
+	if privateKeyFile != "" {
+		private, err = readPrivateKey(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if publicKeyFile != "" {
+		public, err = readPublicKey(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if alg == "RS256" {
+		s := &RS256{}
+		if private != nil {
+			key, ok := private.(*rsa.PrivateKey)
+			if !ok {
+				return nil, errors.New("jwt: RS256 private key is not an RSA key")
+			}
+			s.PrivateKey, s.PublicKey = key, &key.PublicKey
+		}
+		if public != nil {
+			key, ok := public.(*rsa.PublicKey)
+			if !ok {
+				return nil, errors.New("jwt: RS256 public key is not an RSA key")
+			}
+			s.PublicKey = key
+		}
+		if s.PublicKey == nil {
+			return nil, errors.New("jwt: RS256 needs a private or public key")
+		}
+		return s, nil
+	}
+
+	s := &EdDSA{}
+	if private != nil {
+		key, ok := private.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("jwt: EdDSA private key is not an Ed25519 key")
+		}
+		s.PrivateKey, s.PublicKey = key, key.Public().(ed25519.PublicKey)
+	}
+	if public != nil {
+		key, ok := public.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("jwt: EdDSA public key is not an Ed25519 key")
+		}
+		s.PublicKey = key
+	}
+	if s.PublicKey == nil {
+		return nil, errors.New("jwt: EdDSA needs a private or public key")
+	}
+	return s, nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: %s is not PEM encoded", file)
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (interface{}, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+func readPublicKey(file string) (interface{}, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
@@ -17,6 +18,7 @@ import (
 type Mail struct {
 	Domain      string
 	Templates   string // Templates is the path to the email templates
+	TemplatesFS fs.FS  // if set, the email templates are read from it instead of Templates
 	Host        string
 	Port        int
 	Username    string
@@ -172,6 +174,10 @@ func (m *Mail) addAPIAttachments(msg Message, tx *apiMail.Transmission) error {
 // SendUsingSMTP builds and sends an email message using SMTP. This is called by ListenForMail,
 // and can also be called directly when necessary
 func (m *Mail) SendUsingSMTP(msg Message) error {
+	if msg.From == "" {
+		msg.From = m.FromAddress
+	}
+
 	formattedMessage, err := m.buildHTMLMessage(msg)
 	if err != nil {
 		return err
@@ -235,9 +241,7 @@ func (m *Mail) getEncryption(e string) smtpMail.Encryption {
 
 // buildHTMLMessage creates the html version of the message
 func (m *Mail) buildHTMLMessage(msg Message) (string, error) {
-	templateToRender := fmt.Sprintf("%s/%s.html.tmpl", m.Templates, msg.Template)
-
-	t, err := template.New("email-html").ParseFiles(templateToRender)
+	t, err := m.parseTemplate(msg.Template + ".html.tmpl")
 	if err != nil {
 		return "", err
 	}
@@ -258,9 +262,7 @@ func (m *Mail) buildHTMLMessage(msg Message) (string, error) {
 
 // buildPlainTextMessage creates the plaintext version of the message
 func (m *Mail) buildPlainTextMessage(msg Message) (string, error) {
-	templateToRender := fmt.Sprintf("%s/%s.plain.tmpl", m.Templates, msg.Template)
-
-	t, err := template.New("email-html").ParseFiles(templateToRender)
+	t, err := m.parseTemplate(msg.Template + ".plain.tmpl")
 	if err != nil {
 		return "", err
 	}
@@ -275,6 +277,14 @@ func (m *Mail) buildPlainTextMessage(msg Message) (string, error) {
 	return plainMessage, nil
 }
 
+// parseTemplate parses a template file from TemplatesFS or the Templates folder
+func (m *Mail) parseTemplate(file string) (*template.Template, error) {
+	if m.TemplatesFS != nil {
+		return template.New("email-html").ParseFS(m.TemplatesFS, file)
+	}
+	return template.New("email-html").ParseFiles(fmt.Sprintf("%s/%s", m.Templates, file))
+}
+
 // inlineCSS takes html input as a string, and inlines css where possible
 func (m *Mail) inlineCSS(s string) (string, error) {
 	options := premailer.Options{
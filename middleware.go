@@ -5,10 +5,18 @@ import (
 	"strconv"
 
 	"github.com/justinas/nosurf"
+	"github.com/saalikmubeen/goravel/session"
 )
 
 func (g *Goravel) SessionLoad(next http.Handler) http.Handler {
 	g.InfoLog.Println("Session middleware loaded")
+
+	// the cookie store keeps the session in the cookie itself,
+	// so it needs its own middleware to write it back
+	if store, ok := g.Session.Store.(*session.CookieStore); ok {
+		return store.LoadAndSave(g.Session, next)
+	}
+
 	return g.Session.LoadAndSave(next)
 }
 
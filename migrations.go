@@ -1,18 +1,25 @@
 package goravel
 
 import (
-	"fmt"
-
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
+// newMigrate reads the migrations from the migrations folder of FS, or of RootPath
+func (g *Goravel) newMigrate(dsn string) (*migrate.Migrate, error) {
+	source, err := iofs.New(g.files(), "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithSourceInstance("iofs", source, dsn)
+}
+
 func (g *Goravel) MigrateUp(dsn string) error {
 
-	m, err := migrate.New(fmt.Sprintf("file://%s/migrations", g.RootPath), dsn)
+	m, err := g.newMigrate(dsn)
 
 	if err != nil {
 		return err
@@ -33,7 +40,7 @@ func (g *Goravel) MigrateUp(dsn string) error {
 
 func (g *Goravel) MigrateDownAll(dsn string) error {
 
-	m, err := migrate.New(fmt.Sprintf("file://%s/migrations", g.RootPath), dsn)
+	m, err := g.newMigrate(dsn)
 
 	if err != nil {
 		return err
@@ -54,7 +61,7 @@ func (g *Goravel) MigrateDownAll(dsn string) error {
 // Steps runs n steps of migrations. If n > 0, it will run n steps of "up" migrations.
 // If n < 0, it will run n steps of "down" migrations.
 func (g *Goravel) MigrateSteps(n int, dsn string) error {
-	m, err := migrate.New("file://"+g.RootPath+"/migrations", dsn)
+	m, err := g.newMigrate(dsn)
 	if err != nil {
 		return err
 	}
@@ -70,7 +77,7 @@ func (g *Goravel) MigrateSteps(n int, dsn string) error {
 
 // MigrateForce forces the migration to the immediate last version
 func (g *Goravel) MigrateForce(dsn string) error {
-	m, err := migrate.New("file://"+g.RootPath+"/migrations", dsn)
+	m, err := g.newMigrate(dsn)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,47 @@
+package goravel
+
+import (
+	"os"
+	"strings"
+
+	"github.com/saalikmubeen/goravel/oauth"
+)
+
+// createOAuth sets up social login. A provider is enabled by setting its
+// client ID, e.g. OAUTH_GITHUB_CLIENT_ID, OAUTH_GITHUB_CLIENT_SECRET and
+// OAUTH_GITHUB_REDIRECT_URL; the redirect URL defaults to
+// <APP_URL>/auth/<provider>/callback.
+func (g *Goravel) createOAuth() *oauth.OAuth {
+	o := &oauth.OAuth{Session: g.Session}
+
+	if g.DB.Pool != nil {
+		o.Accounts = &oauth.SQLAccountStore{DB: g.DB.Pool, DatabaseType: g.DB.DatabaseType}
+	}
+
+	if c, ok := oauthConfig("github"); ok {
+		o.Register(&oauth.GitHub{OAuthConfig: c})
+	}
+	if c, ok := oauthConfig("google"); ok {
+		o.Register(&oauth.Google{OAuthConfig: c})
+	}
+
+	return o
+}
+
+// oauthConfig reads the client configuration of a provider from the environment
+func oauthConfig(provider string) (oauth.Config, bool) {
+	prefix := "OAUTH_" + strings.ToUpper(provider) + "_"
+
+	c := oauth.Config{
+		ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		Scopes:       envList(prefix + "SCOPES"),
+	}
+
+	if c.RedirectURL == "" {
+		c.RedirectURL = strings.TrimSuffix(os.Getenv("APP_URL"), "/") + "/auth/" + provider + "/callback"
+	}
+
+	return c, c.ClientID != ""
+}
@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrAccountNotLinked is returned when a provider account isn't linked to a user yet
+	ErrAccountNotLinked = errors.New("oauth: account is not linked to a user")
+	// ErrUnverifiedEmail is returned by ResolveUser when a user with the account's
+	// email exists, but the provider didn't verify the email
+	ErrUnverifiedEmail = errors.New("oauth: email is not verified by the provider")
+	// ErrMissingEmail is returned by ResolveUser when a new user would have
+	// to be created, but the provider didn't share an email
+	ErrMissingEmail = errors.New("oauth: provider did not share an email")
+	// ErrNoAccountStore is returned by ResolveUser when OAuth has no
+	// Accounts, e.g. because the app has no database
+	ErrNoAccountStore = errors.New("oauth: no account store configured")
+)
+
+// AccountStore links provider accounts to users
+type AccountStore interface {
+	// FindUserID returns the user a provider account is linked to
+	FindUserID(provider, providerUserID string) (int, error)
+	Link(userID int, user *User) error
+	Unlink(userID int, provider string) error
+}
+
+// SQLAccountStore keeps the links in the social_accounts table created by "goravel make auth"
+type SQLAccountStore struct {
+	DB           *sql.DB
+	DatabaseType string
+}
+
+func (s *SQLAccountStore) postgres() bool {
+	return s.DatabaseType == "postgres" || s.DatabaseType == "postgresql" || s.DatabaseType == "pgx"
+}
+
+// placeholder returns the n-th (1 based) bind parameter for the database in use
+func (s *SQLAccountStore) placeholder(n int) string {
+	if s.postgres() {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// FindUserID returns the user a provider account is linked to
+func (s *SQLAccountStore) FindUserID(provider, providerUserID string) (int, error) {
+	query := fmt.Sprintf("SELECT user_id FROM social_accounts WHERE provider = %s AND provider_user_id = %s",
+		s.placeholder(1), s.placeholder(2))
+
+	var id int
+	err := s.DB.QueryRow(query, provider, providerUserID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrAccountNotLinked
+	}
+	return id, err
+}
+
+// Link links a provider account to a user, updating the saved email if it is already linked
+func (s *SQLAccountStore) Link(userID int, user *User) error {
+	upsert := "ON DUPLICATE KEY UPDATE email = VALUES(email), updated_at = VALUES(updated_at)"
+	if s.postgres() {
+		upsert = "ON CONFLICT (provider, provider_user_id) DO UPDATE SET email = EXCLUDED.email, updated_at = EXCLUDED.updated_at"
+	}
+
+	query := fmt.Sprintf(`INSERT INTO social_accounts (user_id, provider, provider_user_id, email, created_at, updated_at)
+		VALUES (%s, %s, %s, %s, %s, %s) %s`,
+		s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5), s.placeholder(6), upsert)
+
+	now := time.Now()
+	_, err := s.DB.Exec(query, userID, user.Provider, user.ID, user.Email, now, now)
+	return err
+}
+
+// Unlink removes a user's link to a provider
+func (s *SQLAccountStore) Unlink(userID int, provider string) error {
+	query := fmt.Sprintf("DELETE FROM social_accounts WHERE user_id = %s AND provider = %s", s.placeholder(1), s.placeholder(2))
+	_, err := s.DB.Exec(query, userID, provider)
+	return err
+}
+
+// ResolveUser returns the ID of the local user for a provider account. An
+// account that was linked before is used as is. Otherwise it is linked to the
+// user with the same email, but only if the provider verified that email, so
+// nobody can take over an account by registering its email elsewhere. If
+// there is no such user, create makes one; accounts without an email are
+// refused with ErrMissingEmail instead.
+//
+// findByEmail returns 0 and no error when there is no user with the email.
+func (o *OAuth) ResolveUser(u *User, findByEmail func(email string) (int, error), create func(u *User) (int, error)) (int, error) {
+	if o.Accounts == nil {
+		return 0, ErrNoAccountStore
+	}
+	if u.ID == "" {
+		return 0, ErrMissingUserID
+	}
+
+	id, err := o.Accounts.FindUserID(u.Provider, u.ID)
+	if err == nil {
+		return id, o.Accounts.Link(id, u) // keeps the saved email current
+	}
+	if !errors.Is(err, ErrAccountNotLinked) {
+		return 0, err
+	}
+
+	if u.Email != "" {
+		id, err = findByEmail(u.Email)
+		if err != nil {
+			return 0, err
+		}
+		if id != 0 && !u.EmailVerified {
+			return 0, ErrUnverifiedEmail
+		}
+	}
+
+	if id == 0 {
+		if u.Email == "" {
+			return 0, ErrMissingEmail
+		}
+		id, err = create(u)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, o.Accounts.Link(id, u)
+}
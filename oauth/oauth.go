@@ -0,0 +1,277 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+var (
+	// ErrUnknownProvider is returned for provider names that aren't configured
+	ErrUnknownProvider = errors.New("oauth: unknown provider")
+	// ErrInvalidState is returned when the callback's state doesn't match the
+	// one saved in the session, e.g. for a forged or replayed callback
+	ErrInvalidState = errors.New("oauth: invalid state")
+	// ErrAccessDenied is returned when the user declined on the provider's page
+	ErrAccessDenied = errors.New("oauth: access denied")
+)
+
+// session keys of a flow in progress
+const (
+	stateKey    = "oauth.state"
+	verifierKey = "oauth.verifier"
+	providerKey = "oauth.provider"
+)
+
+// Config is the OAuth2 client configuration of one provider
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	RedirectURL  string // our callback, e.g. https://example.com/auth/github/callback
+	Scopes       []string
+}
+
+// Token is the provider's response to a code exchange
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// User is the profile a provider returns for the logged in account
+type User struct {
+	Provider      string
+	ID            string // the user's ID at the provider
+	Email         string
+	EmailVerified bool // only link to an existing account by email if true
+	Name          string
+	AvatarURL     string
+	Raw           map[string]interface{}
+}
+
+// Provider is an OAuth2 identity provider. GitHub, Google and Generic are
+// built in; apps can add their own.
+type Provider interface {
+	Name() string
+	Config() *Config
+	// FetchUser loads the profile of the user an access token belongs to
+	FetchUser(ctx context.Context, client *http.Client, token *Token) (*User, error)
+}
+
+// OAuth runs the authorization code flow with PKCE. The state and code
+// verifier of a flow in progress are kept in the session.
+type OAuth struct {
+	Session    *scs.SessionManager
+	Providers  map[string]Provider
+	Accounts   AccountStore // links provider accounts to users
+	HTTPClient *http.Client // a client with a 10 second timeout if nil
+}
+
+// Register adds a provider
+func (o *OAuth) Register(p Provider) {
+	if o.Providers == nil {
+		o.Providers = make(map[string]Provider)
+	}
+	o.Providers[p.Name()] = p
+}
+
+// Redirect sends the user to the provider's consent page
+func (o *OAuth) Redirect(w http.ResponseWriter, r *http.Request, provider string) error {
+	p, ok := o.Providers[provider]
+	if !ok {
+		return ErrUnknownProvider
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return err
+	}
+
+	o.Session.Put(r.Context(), stateKey, state)
+	o.Session.Put(r.Context(), verifierKey, verifier)
+	o.Session.Put(r.Context(), providerKey, provider)
+
+	http.Redirect(w, r, AuthCodeURL(p.Config(), state, verifier), http.StatusFound)
+	return nil
+}
+
+// Callback handles the provider redirecting back to us: it checks the state,
+// exchanges the code for a token and loads the user's profile
+func (o *OAuth) Callback(r *http.Request, provider string) (*User, *Token, error) {
+	p, ok := o.Providers[provider]
+	if !ok {
+		return nil, nil, ErrUnknownProvider
+	}
+
+	// the saved flow can only be used once
+	ctx := r.Context()
+	state := o.Session.PopString(ctx, stateKey)
+	verifier := o.Session.PopString(ctx, verifierKey)
+	savedProvider := o.Session.PopString(ctx, providerKey)
+
+	q := r.URL.Query()
+	if state == "" || savedProvider != provider ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(q.Get("state"))) != 1 {
+		return nil, nil, ErrInvalidState
+	}
+
+	if e := q.Get("error"); e != "" {
+		if e == "access_denied" {
+			return nil, nil, ErrAccessDenied
+		}
+		return nil, nil, fmt.Errorf("oauth: %s: %s", e, q.Get("error_description"))
+	}
+
+	token, err := Exchange(ctx, o.client(), p.Config(), q.Get("code"), verifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := p.FetchUser(ctx, o.client(), token)
+	if err != nil {
+		return nil, nil, err
+	}
+	user.Provider = provider
+
+	return user, token, nil
+}
+
+func (o *OAuth) client() *http.Client {
+	if o.HTTPClient == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return o.HTTPClient
+}
+
+// AuthCodeURL returns the provider's consent page URL for a flow
+func AuthCodeURL(c *Config, state, verifier string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", c.ClientID)
+	v.Set("redirect_uri", c.RedirectURL)
+	v.Set("state", state)
+	v.Set("code_challenge", codeChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	sep := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.AuthURL + sep + v.Encode()
+}
+
+// Exchange trades an authorization code for a token
+func Exchange(ctx context.Context, client *http.Client, c *Config, code, verifier string) (*Token, error) {
+	if code == "" {
+		return nil, errors.New("oauth: no authorization code")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.RedirectURL)
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var body struct {
+		Token
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := doJSON(client, req, &body); err != nil {
+		return nil, err
+	}
+
+	if body.Error != "" {
+		return nil, fmt.Errorf("oauth: token exchange: %s: %s", body.Error, body.ErrorDescription)
+	}
+	if body.AccessToken == "" {
+		return nil, errors.New("oauth: token exchange returned no access token")
+	}
+
+	return &body.Token, nil
+}
+
+// GetJSON fetches an API endpoint with a bearer token and decodes the response into v
+func GetJSON(ctx context.Context, client *http.Client, endpoint string, token *Token, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	return doJSON(client, req, v)
+}
+
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	// token endpoints report errors with a 400 and a JSON body, which the caller inspects
+	if res.StatusCode >= 300 && res.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("oauth: %s %s: %s", req.Method, req.URL.Host+req.URL.Path, res.Status)
+	}
+
+	// numbers are kept as json.Number, so large numeric user IDs survive fmt.Sprint
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// randomString returns 32 random bytes, base64url encoded, as used for
+// states and PKCE code verifiers
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// codeChallenge derives the S256 PKCE challenge of a verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
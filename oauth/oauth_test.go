@@ -0,0 +1,267 @@
+package oauth
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// fakeProvider is an authorization server with authorize, token and
+// userinfo endpoints
+type fakeProvider struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	challenges map[string]string // code challenges by authorization code
+	verifiers  []string          // code verifiers the token endpoint was sent
+	profile    string            // the userinfo response
+}
+
+func newFakeProvider(t *testing.T, profile string) *fakeProvider {
+	p := &fakeProvider{challenges: map[string]string{}, profile: profile}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+			http.Error(w, "PKCE is required", http.StatusBadRequest)
+			return
+		}
+
+		p.mu.Lock()
+		code := fmt.Sprintf("code-%d", len(p.challenges))
+		p.challenges[code] = q.Get("code_challenge")
+		p.mu.Unlock()
+
+		v := url.Values{"code": {code}, "state": {q.Get("state")}}
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+v.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		verifier := r.PostFormValue("code_verifier")
+		p.verifiers = append(p.verifiers, verifier)
+
+		challenge, ok := p.challenges[r.PostFormValue("code")]
+		delete(p.challenges, r.PostFormValue("code"))
+		if !ok || codeChallenge(verifier) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"bad code or verifier"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"access-token","token_type":"Bearer"}`)
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, p.profile)
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// newFlow starts an app that logs in through provider, and a browser for it.
+// If resolve is set, the callback resolves the local user with it.
+func newFlow(t *testing.T, provider *fakeProvider, resolve func(*User) (int, error)) (*OAuth, *httptest.Server, *http.Client) {
+	sm := scs.New()
+	o := &OAuth{Session: sm}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if err := o.Redirect(w, r, "fake"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		user, _, err := o.Callback(r, "fake")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if resolve == nil {
+			fmt.Fprintf(w, "%s %s %s %v", user.Provider, user.ID, user.Email, user.EmailVerified)
+			return
+		}
+
+		id, err := resolve(user)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, id)
+	})
+
+	app := httptest.NewServer(sm.LoadAndSave(mux))
+	t.Cleanup(app.Close)
+
+	o.Register(&Generic{
+		ProviderName: "fake",
+		OAuthConfig: Config{
+			ClientID:    "client",
+			AuthURL:     provider.URL + "/authorize",
+			TokenURL:    provider.URL + "/token",
+			RedirectURL: app.URL + "/callback",
+		},
+		UserInfoURL: provider.URL + "/userinfo",
+	})
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o, app, &http.Client{Jar: jar}
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, string(body)
+}
+
+func TestCallbackSendsTheVerifier(t *testing.T) {
+	provider := newFakeProvider(t, `{"sub":"42","email":"user@example.com","email_verified":true}`)
+	_, app, browser := newFlow(t, provider, nil)
+
+	status, body := get(t, browser, app.URL+"/login")
+	if status != http.StatusOK || body != "fake 42 user@example.com true" {
+		t.Fatalf("login ended with %d %q", status, body)
+	}
+	if len(provider.verifiers) != 1 || provider.verifiers[0] == "" {
+		t.Fatalf("token endpoint was sent verifiers %q", provider.verifiers)
+	}
+}
+
+func TestCallbackChecksState(t *testing.T) {
+	provider := newFakeProvider(t, `{"sub":"42"}`)
+	_, app, browser := newFlow(t, provider, nil)
+
+	// stop at the provider's redirect back to us, to tamper with it
+	browser.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Path == "/callback" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	res, err := browser.Get(app.URL + "/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	callback, err := url.Parse(res.Header.Get("Location"))
+	if err != nil || callback.Query().Get("code") == "" {
+		t.Fatalf("provider redirected to %q", res.Header.Get("Location"))
+	}
+	browser.CheckRedirect = nil
+
+	forged := url.Values{"code": {callback.Query().Get("code")}, "state": {"forged"}}
+	if status, body := get(t, browser, app.URL+"/callback?"+forged.Encode()); status != http.StatusForbidden {
+		t.Fatalf("callback with a forged state got %d %q", status, body)
+	}
+
+	// the failed attempt used up the saved flow, so the real callback fails too
+	if status, _ := get(t, browser, callback.String()); status != http.StatusForbidden {
+		t.Fatalf("callback after a failed attempt got %d", status)
+	}
+
+	// as does a callback in a session that never started a flow
+	if status, _ := get(t, &http.Client{}, callback.String()); status != http.StatusForbidden {
+		t.Fatalf("callback without a flow got %d", status)
+	}
+
+	if len(provider.verifiers) != 0 {
+		t.Fatalf("codes were exchanged %d times without a valid state", len(provider.verifiers))
+	}
+}
+
+// memoryAccounts is an AccountStore in memory
+type memoryAccounts map[string]int
+
+func (a memoryAccounts) FindUserID(provider, providerUserID string) (int, error) {
+	id, ok := a[provider+":"+providerUserID]
+	if !ok {
+		return 0, ErrAccountNotLinked
+	}
+	return id, nil
+}
+
+func (a memoryAccounts) Link(userID int, user *User) error {
+	a[user.Provider+":"+user.ID] = userID
+	return nil
+}
+
+func (a memoryAccounts) Unlink(userID int, provider string) error {
+	for key, id := range a {
+		if id == userID {
+			delete(a, key)
+		}
+	}
+	return nil
+}
+
+func TestResolveUserLinksOnlyVerifiedEmails(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		profile string
+		linked  map[string]int
+		want    string // the user ID, or the error
+	}{
+		{"verified email of an existing user", `{"sub":"42","email":"user@example.com","email_verified":true}`, nil, "1"},
+		{"unverified email of an existing user", `{"sub":"42","email":"user@example.com"}`, nil, ErrUnverifiedEmail.Error()},
+		{"linked account", `{"sub":"42","email":"changed@example.com"}`, map[string]int{"fake:42": 1}, "1"},
+		{"new user", `{"sub":"42","email":"new@example.com"}`, nil, "2"},
+		{"no email", `{"sub":"42"}`, nil, ErrMissingEmail.Error()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			users := map[string]int{"user@example.com": 1}
+			accounts := memoryAccounts{}
+			for key, id := range test.linked {
+				accounts[key] = id
+			}
+
+			var o *OAuth
+			o, app, browser := newFlow(t, newFakeProvider(t, test.profile), func(u *User) (int, error) {
+				return o.ResolveUser(u,
+					func(email string) (int, error) { return users[email], nil },
+					func(u *User) (int, error) {
+						users[u.Email] = len(users) + 1
+						return users[u.Email], nil
+					})
+			})
+			o.Accounts = accounts
+
+			_, body := get(t, browser, app.URL+"/login")
+			if strings.TrimSpace(body) != test.want {
+				t.Fatalf("login ended with %q, want %q", body, test.want)
+			}
+
+			// failed logins must not link the account
+			id, _ := accounts.FindUserID("fake", "42")
+			if want, _ := strconv.Atoi(test.want); id != want {
+				t.Fatalf("account linked to user %d, want %d", id, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ErrMissingUserID is returned by FetchUser when the profile has no user ID,
+// which accounts are linked by
+var ErrMissingUserID = errors.New("oauth: profile has no user id")
+
+// GitHub signs users in with their GitHub account
+type GitHub struct {
+	OAuthConfig Config // AuthURL and TokenURL default to github.com
+	APIURL      string // https://api.github.com if not set
+}
+
+func (g *GitHub) Name() string { return "github" }
+
+func (g *GitHub) Config() *Config {
+	c := g.OAuthConfig
+	if c.AuthURL == "" {
+		c.AuthURL = "https://github.com/login/oauth/authorize"
+	}
+	if c.TokenURL == "" {
+		c.TokenURL = "https://github.com/login/oauth/access_token"
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"read:user", "user:email"}
+	}
+	return &c
+}
+
+func (g *GitHub) FetchUser(ctx context.Context, client *http.Client, token *Token) (*User, error) {
+	api := g.APIURL
+	if api == "" {
+		api = "https://api.github.com"
+	}
+
+	var raw map[string]interface{}
+	if err := GetJSON(ctx, client, api+"/user", token, &raw); err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		ID:        id(raw, "id"),
+		Name:      str(raw, "name"),
+		AvatarURL: str(raw, "avatar_url"),
+		Raw:       raw,
+	}
+	if user.ID == "" {
+		return nil, ErrMissingUserID
+	}
+	if user.Name == "" {
+		user.Name = str(raw, "login")
+	}
+
+	// the profile email may be hidden or unverified; the emails endpoint says which is which
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := GetJSON(ctx, client, api+"/user/emails", token, &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary {
+				user.Email, user.EmailVerified = e.Email, e.Verified
+			}
+		}
+	}
+	if user.Email == "" {
+		user.Email = str(raw, "email")
+	}
+
+	return user, nil
+}
+
+// Google signs users in with their Google account
+type Google struct {
+	OAuthConfig Config // AuthURL and TokenURL default to Google's
+	UserInfoURL string // Google's OpenID Connect userinfo endpoint if not set
+}
+
+func (g *Google) Name() string { return "google" }
+
+func (g *Google) Config() *Config {
+	c := g.OAuthConfig
+	if c.AuthURL == "" {
+		c.AuthURL = "https://accounts.google.com/o/oauth2/v2/auth"
+	}
+	if c.TokenURL == "" {
+		c.TokenURL = "https://oauth2.googleapis.com/token"
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &c
+}
+
+func (g *Google) FetchUser(ctx context.Context, client *http.Client, token *Token) (*User, error) {
+	endpoint := g.UserInfoURL
+	if endpoint == "" {
+		endpoint = "https://openidconnect.googleapis.com/v1/userinfo"
+	}
+
+	var raw map[string]interface{}
+	if err := GetJSON(ctx, client, endpoint, token, &raw); err != nil {
+		return nil, err
+	}
+
+	if str(raw, "sub") == "" {
+		return nil, ErrMissingUserID
+	}
+
+	verified, _ := raw["email_verified"].(bool)
+	return &User{
+		ID:            str(raw, "sub"),
+		Email:         str(raw, "email"),
+		EmailVerified: verified,
+		Name:          str(raw, "name"),
+		AvatarURL:     str(raw, "picture"),
+		Raw:           raw,
+	}, nil
+}
+
+// Generic works with any provider that has a JSON userinfo endpoint, e.g. an
+// OpenID Connect server, or a local stub server in tests. Profile fields are
+// read from the standard OpenID Connect claims.
+type Generic struct {
+	ProviderName string
+	OAuthConfig  Config
+	UserInfoURL  string
+}
+
+func (g *Generic) Name() string { return g.ProviderName }
+
+func (g *Generic) Config() *Config { return &g.OAuthConfig }
+
+func (g *Generic) FetchUser(ctx context.Context, client *http.Client, token *Token) (*User, error) {
+	var raw map[string]interface{}
+	if err := GetJSON(ctx, client, g.UserInfoURL, token, &raw); err != nil {
+		return nil, err
+	}
+
+	userID := str(raw, "sub")
+	if userID == "" {
+		userID = id(raw, "id")
+	}
+	if userID == "" {
+		return nil, ErrMissingUserID
+	}
+
+	verified, _ := raw["email_verified"].(bool)
+	return &User{
+		ID:            userID,
+		Email:         str(raw, "email"),
+		EmailVerified: verified,
+		Name:          str(raw, "name"),
+		AvatarURL:     str(raw, "picture"),
+		Raw:           raw,
+	}, nil
+}
+
+// str reads a string field of a decoded JSON object
+func str(raw map[string]interface{}, key string) string {
+	s, _ := raw[key].(string)
+	return s
+}
+
+// id reads an ID field of a decoded JSON object, which may be a string or a
+// number; numbers are decoded as float64 and must not be printed as 1.2e+07.
+// It returns "" if the field is missing or of another type.
+func id(raw map[string]interface{}, key string) string {
+	switch v := raw[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
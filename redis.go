@@ -0,0 +1,244 @@
+package goravel
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+// loadRedisConfig reads the redis settings from the environment
+func loadRedisConfig() redisConfig {
+	return redisConfig{
+		mode:            strings.ToLower(os.Getenv("REDIS_MODE")),
+		host:            os.Getenv("REDIS_HOST"),
+		username:        os.Getenv("REDIS_USERNAME"),
+		password:        os.Getenv("REDIS_PASSWORD"),
+		prefix:          os.Getenv("REDIS_PREFIX"),
+		database:        envInt("REDIS_DB", 0),
+		maxIdle:         envInt("REDIS_MAX_IDLE", 50),
+		maxActive:       envInt("REDIS_MAX_ACTIVE", 10000),
+		idleTimeout:     envSeconds("REDIS_IDLE_TIMEOUT", 240*time.Second),
+		maxConnLifetime: envSeconds("REDIS_MAX_CONN_LIFETIME", 0),
+		connectTimeout:  envSeconds("REDIS_CONNECT_TIMEOUT", 5*time.Second),
+		readTimeout:     envSeconds("REDIS_READ_TIMEOUT", 0),
+		writeTimeout:    envSeconds("REDIS_WRITE_TIMEOUT", 0),
+		wait:            envBool("REDIS_POOL_WAIT", false),
+		cluster: redisClusterConfig{
+			addrs: envList("REDIS_CLUSTER_ADDRS"),
+		},
+		sentinel: redisSentinelConfig{
+			addrs:      envList("REDIS_SENTINEL_ADDRS"),
+			masterName: os.Getenv("REDIS_SENTINEL_MASTER"),
+			password:   os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		},
+		tls: redisTLSConfig{
+			enabled:    envBool("REDIS_TLS", false),
+			skipVerify: envBool("REDIS_TLS_SKIP_VERIFY", false),
+			serverName: os.Getenv("REDIS_TLS_SERVER_NAME"),
+			caCert:     os.Getenv("REDIS_TLS_CA_CERT"),
+			cert:       os.Getenv("REDIS_TLS_CERT"),
+			key:        os.Getenv("REDIS_TLS_KEY"),
+		},
+	}
+}
+
+// createRedisPool creates the redis connection pool shared by the cache and the
+// redis session store. Depending on REDIS_MODE, connections either go straight to
+// REDIS_HOST, to whichever node the sentinels currently report as master, or to
+// the cluster master serving goravel's keys.
+func (g *Goravel) createRedisPool() (*redis.Pool, error) {
+	cfg := g.config.redis
+
+	tlsConfig, err := cfg.tls.build()
+	if err != nil {
+		return nil, err
+	}
+
+	// options shared by connections to redis itself and to the sentinels
+	baseOptions := []redis.DialOption{
+		redis.DialConnectTimeout(cfg.connectTimeout),
+		redis.DialReadTimeout(cfg.readTimeout),
+		redis.DialWriteTimeout(cfg.writeTimeout),
+	}
+	if tlsConfig != nil {
+		baseOptions = append(baseOptions,
+			redis.DialUseTLS(true),
+			redis.DialTLSConfig(tlsConfig),
+			redis.DialTLSSkipVerify(cfg.tls.skipVerify),
+		)
+	}
+
+	options := append([]redis.DialOption{}, baseOptions...)
+	options = append(options,
+		redis.DialDatabase(cfg.database),
+		redis.DialPassword(cfg.password),
+	)
+	if cfg.username != "" {
+		options = append(options, redis.DialUsername(cfg.username))
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:         cfg.maxIdle,
+		MaxActive:       cfg.maxActive,
+		IdleTimeout:     cfg.idleTimeout,
+		MaxConnLifetime: cfg.maxConnLifetime,
+		Wait:            cfg.wait,
+	}
+
+	switch cfg.mode {
+	case "", "standalone":
+		pool.Dial = func() (redis.Conn, error) {
+			return redis.Dial("tcp", cfg.host, options...)
+		}
+
+		pool.TestOnBorrow = func(conn redis.Conn, t time.Time) error {
+			_, err := conn.Do("PING")
+			return err
+		}
+
+	case "sentinel":
+		if len(cfg.sentinel.addrs) == 0 || cfg.sentinel.masterName == "" {
+			return nil, errors.New("redis sentinel mode requires REDIS_SENTINEL_ADDRS and REDIS_SENTINEL_MASTER")
+		}
+
+		sentinelOptions := append([]redis.DialOption{}, baseOptions...)
+		if cfg.sentinel.password != "" {
+			sentinelOptions = append(sentinelOptions, redis.DialPassword(cfg.sentinel.password))
+		}
+
+		pool.Dial = func() (redis.Conn, error) {
+			addr, err := sentinelMasterAddr(cfg.sentinel, sentinelOptions)
+			if err != nil {
+				return nil, err
+			}
+			return redis.Dial("tcp", addr, options...)
+		}
+
+		// after a failover the old master is demoted to a replica; checking the
+		// role makes the pool drop those connections and dial the new master
+		pool.TestOnBorrow = func(conn redis.Conn, t time.Time) error {
+			role, err := redis.Values(conn.Do("ROLE"))
+			if err != nil {
+				return err
+			}
+			if len(role) == 0 {
+				return errors.New("redis: empty ROLE reply")
+			}
+			if r, _ := redis.String(role[0], nil); r != "master" {
+				return fmt.Errorf("redis: connection is to a %s, not the master", r)
+			}
+			return nil
+		}
+
+	case "cluster":
+		seeds := cfg.clusterSeeds()
+		if len(seeds) == 0 {
+			return nil, errors.New("redis cluster mode requires REDIS_CLUSTER_ADDRS or REDIS_HOST")
+		}
+		if cfg.database != 0 {
+			return nil, errors.New("redis cluster mode only supports REDIS_DB=0")
+		}
+
+		dialer := &clusterDialer{seeds: seeds, hashTag: cfg.keyPrefix(), options: options}
+		pool.Dial = dialer.dial
+
+		pool.TestOnBorrow = func(conn redis.Conn, t time.Time) error {
+			_, err := conn.Do("PING")
+			return err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown REDIS_MODE %q; expected standalone, sentinel or cluster", cfg.mode)
+	}
+
+	return pool, nil
+}
+
+func (g *Goravel) createRedisCache() (*cache.RedisCache, error) {
+	pool, err := g.createRedisPool()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheClient := cache.RedisCache{
+		Conn:   pool,
+		Prefix: g.config.redis.keyPrefix(),
+	}
+	return &cacheClient, nil
+}
+
+// sentinelMasterAddr asks each sentinel in turn for the address of the current
+// master, returning the first answer it gets
+func sentinelMasterAddr(cfg redisSentinelConfig, options []redis.DialOption) (string, error) {
+	var lastErr error
+
+	for _, sentinel := range cfg.addrs {
+		addr, err := func() (string, error) {
+			conn, err := redis.Dial("tcp", sentinel, options...)
+			if err != nil {
+				return "", err
+			}
+			defer conn.Close()
+
+			res, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", cfg.masterName))
+			if err != nil {
+				return "", err
+			}
+			if len(res) != 2 {
+				return "", fmt.Errorf("sentinel %s does not know master %q", sentinel, cfg.masterName)
+			}
+
+			return net.JoinHostPort(res[0], res[1]), nil
+		}()
+		if err == nil {
+			return addr, nil
+		}
+		lastErr = err
+	}
+
+	return "", fmt.Errorf("no sentinel could resolve master %q: %w", cfg.masterName, lastErr)
+}
+
+// build turns the TLS settings into a *tls.Config, or nil when TLS is disabled
+func (t redisTLSConfig) build() (*tls.Config, error) {
+	if !t.enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.serverName,
+		InsecureSkipVerify: t.skipVerify,
+	}
+
+	if t.caCert != "" {
+		pem, err := os.ReadFile(t.caCert)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", t.caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.cert != "" || t.key != "" {
+		cert, err := tls.LoadX509KeyPair(t.cert, t.key)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,248 @@
+package goravel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// In cluster mode all of goravel's keys carry the prefix as a hash tag, e.g.
+// "{myapp}:users", so they map to the same hash slot and live on one master.
+// That keeps multi-key commands (MGET, DEL, MULTI/EXEC) and SCAN working on
+// a plain *redis.Pool, at the price of not spreading goravel's own keys over
+// the cluster. Connections go to the master owning that slot and follow it
+// when it moves, e.g. after a failover. Cluster mode therefore buys failover,
+// not capacity: one node holds all of goravel's keys and takes all of its load.
+
+// keyPrefix returns the prefix of goravel's redis keys, hash tagged in cluster mode
+func (c redisConfig) keyPrefix() string {
+	if c.mode != "cluster" {
+		return c.prefix
+	}
+
+	prefix := c.prefix
+	if prefix == "" {
+		prefix = "goravel"
+	}
+	return "{" + prefix + "}"
+}
+
+// clusterSeeds returns the nodes asked for the cluster layout
+func (c redisConfig) clusterSeeds() []string {
+	if len(c.cluster.addrs) > 0 {
+		return c.cluster.addrs
+	}
+	if c.host != "" {
+		return []string{c.host}
+	}
+	return nil
+}
+
+// clusterDialer dials the master serving the slot of goravel's keys
+type clusterDialer struct {
+	seeds   []string
+	hashTag string
+	options []redis.DialOption
+}
+
+// dial connects to the current owner of the slot
+func (d *clusterDialer) dial() (redis.Conn, error) {
+	addr, err := d.masterAddr()
+	if err != nil {
+		return nil, err
+	}
+	return d.dialAddr(addr)
+}
+
+func (d *clusterDialer) dialAddr(addr string) (redis.Conn, error) {
+	conn, err := redis.Dial("tcp", addr, d.options...)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterConn{Conn: conn, dialer: d}, nil
+}
+
+// masterAddr asks each seed node in turn which master serves the slot
+func (d *clusterDialer) masterAddr() (string, error) {
+	var lastErr error
+
+	for _, seed := range d.seeds {
+		addr, err := func() (string, error) {
+			conn, err := redis.Dial("tcp", seed, d.options...)
+			if err != nil {
+				return "", err
+			}
+			defer conn.Close()
+
+			slot, err := redis.Int(conn.Do("CLUSTER", "KEYSLOT", d.hashTag))
+			if err != nil {
+				return "", err
+			}
+
+			ranges, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+			if err != nil {
+				return "", err
+			}
+			return slotMaster(ranges, slot, seed)
+		}()
+		if err == nil {
+			return addr, nil
+		}
+		lastErr = err
+	}
+
+	return "", fmt.Errorf("no cluster node could resolve the master of %s: %w", d.hashTag, lastErr)
+}
+
+// slotMaster finds the master of slot in a CLUSTER SLOTS reply. Each range
+// is [start, end, [ip, port, ...], replicas...]; an empty ip means the node
+// that answered.
+func slotMaster(ranges []interface{}, slot int, seed string) (string, error) {
+	for _, r := range ranges {
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			continue
+		}
+
+		start, _ := redis.Int(fields[0], nil)
+		end, _ := redis.Int(fields[1], nil)
+		if slot < start || slot > end {
+			continue
+		}
+
+		master, err := redis.Values(fields[2], nil)
+		if err != nil || len(master) < 2 {
+			return "", errors.New("redis: malformed CLUSTER SLOTS reply")
+		}
+
+		host, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+		if host == "" || host == "?" {
+			host, _, _ = net.SplitHostPort(seed)
+		}
+		return net.JoinHostPort(host, strconv.Itoa(port)), nil
+	}
+
+	return "", fmt.Errorf("redis: no master serves slot %d", slot)
+}
+
+// clusterConn is a connection to the master of goravel's slot. When the node
+// answers MOVED, the slot has moved to another node: a single command is
+// retried there, while a pipeline in progress fails and the connection is
+// marked broken, so the pool drops it and dials the new master. An ASK reply
+// means the slot is being migrated and the key already went to the new node:
+// a single command is sent there once, after ASKING, and the connection stays
+// with the old master until the migration ends in a MOVED. Pipelines and
+// MULTI blocks get the ASK error back.
+type clusterConn struct {
+	redis.Conn
+	dialer  *clusterDialer
+	pending int // commands sent but not yet read
+	err     error
+}
+
+func (c *clusterConn) Send(cmd string, args ...interface{}) error {
+	c.pending++
+	return c.Conn.Send(cmd, args...)
+}
+
+func (c *clusterConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	if c.pending > 0 {
+		c.pending--
+	}
+	c.check(err)
+	return reply, err
+}
+
+func (c *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	pipelined := c.pending > 0
+	reply, err := c.Conn.Do(cmd, args...)
+	c.pending = 0
+
+	if pipelined || cmd == "" {
+		c.check(err)
+		return reply, err
+	}
+
+	if addr, ask := redirectTo(err, "ASK"); ask {
+		return c.ask(addr, cmd, args...)
+	}
+
+	addr, moved := movedTo(err)
+	if !moved {
+		c.check(err)
+		return reply, err
+	}
+
+	conn, dialErr := redis.Dial("tcp", addr, c.dialer.options...)
+	if dialErr != nil {
+		c.err = dialErr
+		return nil, dialErr
+	}
+	_ = c.Conn.Close()
+	c.Conn = conn
+
+	return c.Conn.Do(cmd, args...)
+}
+
+// ask sends a single command to the node a slot is being migrated to
+func (c *clusterConn) ask(addr, cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := redis.Dial("tcp", addr, c.dialer.options...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do("ASKING"); err != nil {
+		return nil, err
+	}
+	return conn.Do(cmd, args...)
+}
+
+func (c *clusterConn) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.Conn.Err()
+}
+
+// check marks the connection broken when a reply says the slot moved
+func (c *clusterConn) check(err error) {
+	if _, moved := movedTo(err); moved {
+		c.err = err
+		return
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "EXECABORT") {
+		// the queued commands were refused, most likely with MOVED
+		c.err = err
+	}
+}
+
+// movedTo returns the new address from a "MOVED <slot> <host:port>" error
+func movedTo(err error) (string, bool) {
+	return redirectTo(err, "MOVED")
+}
+
+// redirectTo returns the address from a "<kind> <slot> <host:port>" error,
+// where kind is MOVED or ASK
+func redirectTo(err error, kind string) (string, bool) {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return "", false
+	}
+
+	fields := strings.Fields(string(rerr))
+	if len(fields) != 3 || fields[0] != kind {
+		return "", false
+	}
+	return fields[2], true
+}
@@ -0,0 +1,191 @@
+package goravel
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// fakeNode is a redis server that answers every command with reply, which
+// gets the commands it has seen on the connection
+type fakeNode struct {
+	addr string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeNode(t *testing.T, reply func(seen []string) string) *fakeNode {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	n := &fakeNode{addr: l.Addr().String()}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go n.serve(conn, reply)
+		}
+	}()
+	return n
+}
+
+func (n *fakeNode) serve(conn net.Conn, reply func(seen []string) string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	var seen []string
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Join(args, " "))
+		seen = append(seen, command)
+
+		n.mu.Lock()
+		n.commands = append(n.commands, command)
+		n.mu.Unlock()
+
+		if _, err := conn.Write([]byte(reply(seen))); err != nil {
+			return
+		}
+	}
+}
+
+func (n *fakeNode) seen() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string{}, n.commands...)
+}
+
+// readCommand reads one command in the RESP array of bulk strings format
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil { // $<length>
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func dialFakeCluster(t *testing.T, addr string) *clusterConn {
+	t.Helper()
+	conn, err := redis.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &clusterConn{Conn: conn, dialer: &clusterDialer{}}
+}
+
+func TestClusterConnFollowsAsk(t *testing.T) {
+	target := newFakeNode(t, func(seen []string) string {
+		last := len(seen) - 1
+		switch {
+		case seen[last] == "ASKING":
+			return "+OK\r\n"
+		case last > 0 && seen[last-1] == "ASKING":
+			return bulk("migrated")
+		}
+		// the slot isn't ours yet without ASKING
+		return "-MOVED 1 127.0.0.1:1\r\n"
+	})
+	source := newFakeNode(t, func(seen []string) string {
+		if strings.HasPrefix(seen[len(seen)-1], "GET {APP}:MIGRATED") {
+			return "-ASK 1 " + target.addr + "\r\n"
+		}
+		return bulk("local")
+	})
+
+	c := dialFakeCluster(t, source.addr)
+
+	value, err := redis.String(c.Do("GET", "{app}:migrated"))
+	if err != nil || value != "migrated" {
+		t.Fatalf("GET of a migrated key = %q, %v", value, err)
+	}
+
+	// the slot hasn't moved yet, so the connection stays with its node
+	value, err = redis.String(c.Do("GET", "{app}:other"))
+	if err != nil || value != "local" {
+		t.Fatalf("GET after an ASK = %q, %v", value, err)
+	}
+	if c.Err() != nil {
+		t.Fatalf("connection broken after an ASK: %v", c.Err())
+	}
+
+	if got := target.seen(); len(got) != 2 || got[0] != "ASKING" || got[1] != "GET {APP}:MIGRATED" {
+		t.Fatalf("target node got %q, want ASKING and the GET", got)
+	}
+}
+
+func TestClusterConnFollowsMoved(t *testing.T) {
+	target := newFakeNode(t, func(seen []string) string {
+		return bulk("moved")
+	})
+	source := newFakeNode(t, func(seen []string) string {
+		return "-MOVED 1 " + target.addr + "\r\n"
+	})
+
+	c := dialFakeCluster(t, source.addr)
+
+	for i := 0; i < 2; i++ {
+		value, err := redis.String(c.Do("GET", "{app}:key"))
+		if err != nil || value != "moved" {
+			t.Fatalf("GET %d = %q, %v", i, value, err)
+		}
+	}
+
+	// the connection now points at the new master
+	if n := len(source.seen()); n != 1 {
+		t.Fatalf("the old master got %d commands, want 1", n)
+	}
+}
+
+func TestClusterConnBreaksPipelinesOnMoved(t *testing.T) {
+	source := newFakeNode(t, func(seen []string) string {
+		return "-MOVED 1 127.0.0.1:1\r\n"
+	})
+
+	c := dialFakeCluster(t, source.addr)
+
+	if err := c.Send("GET", "{app}:a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Do("GET", "{app}:b"); err == nil {
+		t.Fatal("pipeline hitting MOVED succeeded")
+	}
+	if c.Err() == nil {
+		t.Fatal("connection isn't marked broken after MOVED in a pipeline")
+	}
+}
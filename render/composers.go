@@ -0,0 +1,81 @@
+package render
+
+import (
+	"html/template"
+	"net/http"
+	"path"
+)
+
+// ComposerFunc returns values to add to the data of a view before it is
+// rendered, e.g. the current user or feature flags
+type ComposerFunc func(req *http.Request) map[string]interface{}
+
+type composer struct {
+	pattern string
+	fn      ComposerFunc
+}
+
+// Composer registers fn to run before rendering every view whose name
+// matches pattern, a path.Match glob such as "admin/*", or "*" for all views.
+// Its values end up in TemplateData.Data, and as variables in Jet views.
+// Values set by the handler win over those of composers. Register composers
+// when the app starts.
+func (r *Render) Composer(pattern string, fn ComposerFunc) {
+	r.composers = append(r.composers, composer{pattern: pattern, fn: fn})
+}
+
+// Share adds a value to the data of every view, e.g. the app name
+func (r *Render) Share(key string, value interface{}) {
+	r.Composer("*", func(*http.Request) map[string]interface{} {
+		return map[string]interface{}{key: value}
+	})
+}
+
+// AddFunc makes fn available as a function in every template, e.g. as a
+// global in Jet and in the FuncMap of Go templates. Add functions when the
+// app starts; cached Go templates don't pick up later ones.
+func (r *Render) AddFunc(name string, fn interface{}) {
+	if r.Funcs == nil {
+		r.Funcs = make(template.FuncMap)
+	}
+	r.Funcs[name] = fn
+
+	if e, ok := r.Engine.(funcAdder); ok {
+		e.AddFunc(name, fn)
+	}
+}
+
+// compose runs the composers matching view, adding their values to td.Data.
+// It returns all their values, for engines that also take them as variables.
+func (r *Render) compose(req *http.Request, view string, td *TemplateData) map[string]interface{} {
+	var shared map[string]interface{}
+	for _, c := range r.composers {
+		if !matchView(c.pattern, view) {
+			continue
+		}
+
+		for key, value := range c.fn(req) {
+			if td.Data == nil {
+				td.Data = make(map[string]interface{})
+			}
+			if _, ok := td.Data[key]; !ok {
+				td.Data[key] = value
+			}
+			if shared == nil {
+				shared = make(map[string]interface{})
+			}
+			if _, ok := shared[key]; !ok {
+				shared[key] = value
+			}
+		}
+	}
+	return shared
+}
+
+func matchView(pattern, view string) bool {
+	if pattern == "*" {
+		return true
+	}
+	ok, _ := path.Match(pattern, view)
+	return ok
+}
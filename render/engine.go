@@ -0,0 +1,120 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Engine renders the views of one template language. Go and Jet are built in;
+// other engines, e.g. for templ components or pongo2, are added with
+// RegisterEngine and picked with RENDERER.
+type Engine interface {
+	// Load prepares the engine to render the views in views, the app's views folder
+	Load(views fs.FS) error
+	// Render writes a view to w
+	Render(w io.Writer, v *View) error
+	// Reload drops cached templates, so changed views are read again
+	Reload() error
+}
+
+// View is what an engine gets to render
+type View struct {
+	Name    string // e.g. "home" or "admin/users", without the engine's file extension
+	Request *http.Request
+	// Data holds the handler's data with the default data and the values of
+	// composers added
+	Data *TemplateData
+	// Variables are the engine specific variables passed to Page, e.g. a
+	// jet.VarMap; nil if there are none
+	Variables interface{}
+	// Shared are the values of the composers matching the view, for engines
+	// that take variables besides Data
+	Shared map[string]interface{}
+	// Funcs are the functions bound to the request, such as "can"
+	Funcs template.FuncMap
+}
+
+// EngineFactory creates an engine for a Render, e.g. taking its Debug and Funcs
+type EngineFactory func(r *Render) Engine
+
+// funcAdder is implemented by engines that take functions added with Render.AddFunc
+type funcAdder interface {
+	AddFunc(name string, fn interface{})
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]EngineFactory{
+		"go":  newGoEngine,
+		"jet": newJetEngine,
+	}
+)
+
+// RegisterEngine makes an engine available under name, the value to set
+// RENDERER to. Register engines before the app is created, e.g. in an init
+// function. Registering a name again replaces its engine.
+func RegisterEngine(name string, factory EngineFactory) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+	engines[strings.ToLower(name)] = factory
+}
+
+// Engines returns the names of the registered engines
+func Engines() []string {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func engineFactory(name string) (EngineFactory, bool) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+	factory, ok := engines[strings.ToLower(name)]
+	return factory, ok
+}
+
+// Load creates the engine named by Renderer, unless Engine is already set,
+// and loads the views in views. Without a Renderer there is nothing to load.
+func (r *Render) Load(views fs.FS) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	if r.Engine == nil {
+		if r.Renderer == "" {
+			return nil
+		}
+		factory, _ := engineFactory(r.Renderer)
+		r.Engine = factory(r)
+	}
+
+	if err := r.Engine.Load(views); err != nil {
+		return fmt.Errorf("render: loading the %s views: %w", r.Renderer, err)
+	}
+
+	// keep JetViews pointing at the set the Jet engine renders with
+	if je, ok := r.Engine.(*JetEngine); ok {
+		r.JetViews = je.Set
+	}
+	return nil
+}
+
+// Reload makes the engine read changed views again
+func (r *Render) Reload() error {
+	if r.Engine == nil {
+		return nil
+	}
+	return r.Engine.Reload()
+}
@@ -0,0 +1,128 @@
+package render
+
+import (
+	"context"
+	"encoding/gob"
+	"net/url"
+	"strings"
+)
+
+// Flash message levels
+const (
+	FlashSuccess = "success"
+	FlashInfo    = "info"
+	FlashWarning = "warning"
+	FlashError   = "error"
+)
+
+// session keys the flashed data is kept under until the next render
+const (
+	flashKey      = "__flashes"
+	oldInputKey   = "__oldInput"
+	formErrorsKey = "__formErrors"
+)
+
+// FlashMessage is a one-time message shown on the next rendered page
+type FlashMessage struct {
+	Level   string
+	Message string
+}
+
+func init() {
+	// scs gob encodes session values, which only works for registered types
+	gob.Register([]FlashMessage{})
+	gob.Register(url.Values{})
+	gob.Register(map[string][]string{})
+}
+
+// Flash queues a message of the given level for the next rendered page.
+// Several messages can be queued; they are shown in the order they were added.
+func (r *Render) Flash(ctx context.Context, level, message string) {
+	flashes, _ := r.Session.Get(ctx, flashKey).([]FlashMessage)
+	flashes = append(flashes, FlashMessage{Level: level, Message: message})
+	r.Session.Put(ctx, flashKey, flashes)
+}
+
+// FlashInput keeps submitted form values for the next rendered page, so a
+// form shown again after a redirect can be filled in with what the user typed.
+// Password and CSRF fields are never kept.
+func (r *Render) FlashInput(ctx context.Context, input url.Values) {
+	old := url.Values{}
+	for field, values := range input {
+		lower := strings.ToLower(field)
+		if strings.Contains(lower, "password") || strings.Contains(lower, "csrf") {
+			continue
+		}
+		old[field] = values
+	}
+	r.Session.Put(ctx, oldInputKey, old)
+}
+
+// FlashErrors keeps per-field validation errors for the next rendered page
+func (r *Render) FlashErrors(ctx context.Context, errors map[string][]string) {
+	r.Session.Put(ctx, formErrorsKey, errors)
+}
+
+// HasFlashed reports whether the session holds messages, old input or form
+// errors waiting for the next rendered page
+func (r *Render) HasFlashed(ctx context.Context) bool {
+	for _, key := range []string{"error", "flash", flashKey, oldInputKey, formErrorsKey} {
+		if r.Session.Exists(ctx, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// popFlashed moves everything flashed by the previous request into td
+func (r *Render) popFlashed(ctx context.Context, td *TemplateData) {
+	if flashes, ok := r.Session.Pop(ctx, flashKey).([]FlashMessage); ok {
+		td.Flashes = append(td.Flashes, flashes...)
+	}
+
+	if old, ok := r.Session.Pop(ctx, oldInputKey).(url.Values); ok && td.OldInput == nil {
+		td.OldInput = old
+	}
+
+	if errors, ok := r.Session.Pop(ctx, formErrorsKey).(map[string][]string); ok && td.FormErrors == nil {
+		td.FormErrors = errors
+	}
+}
+
+// FlashesOf returns the flashed messages of one level
+func (td *TemplateData) FlashesOf(level string) []FlashMessage {
+	var flashes []FlashMessage
+	for _, f := range td.Flashes {
+		if f.Level == level {
+			flashes = append(flashes, f)
+		}
+	}
+	return flashes
+}
+
+// Old returns the value previously submitted for a form field, or def if there is none.
+// Use it to fill in a form that is shown again, e.g. {{ .Old("email") }} in Jet.
+func (td *TemplateData) Old(field string, def ...string) string {
+	if td.OldInput != nil {
+		if _, ok := td.OldInput[field]; ok {
+			return td.OldInput.Get(field)
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return ""
+}
+
+// HasError reports whether a form field has a validation error
+func (td *TemplateData) HasError(field string) bool {
+	return len(td.FormErrors[field]) > 0
+}
+
+// FieldError returns the first validation error of a form field
+func (td *TemplateData) FieldError(field string) string {
+	if errs := td.FormErrors[field]; len(errs) > 0 {
+		return errs[0]
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package render
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// GoEngine renders standard Go templates, views/<view>.page.tmpl. Layouts
+// (*.layout.tmpl) and partials (*.partial.tmpl) anywhere under views are
+// available to every page, e.g. a page can start with {{template "base" .}}
+// and define the blocks of a base.layout.tmpl.
+//
+// Template sets are parsed once and cached, except in Debug, where every
+// render re-parses them so template edits show up without a restart.
+//
+// The "can" function checks an ability for the request being rendered. Go
+// templates bind their functions when parsed, so it takes the page's data
+// to find the request: {{ if can . "update" .Data.post }}, or with $ for . inside
+// range and with.
+type GoEngine struct {
+	Debug bool
+	Funcs template.FuncMap // extra functions for all templates
+	// Can backs the "can" function; it never allows anything if nil
+	Can func(r *http.Request, ability string, args ...interface{}) bool
+
+	views fs.FS
+	mu    sync.RWMutex
+	pages map[string]*template.Template
+}
+
+// newGoEngine copies r.Funcs: the engine adds to its own map under its lock,
+// while Render.AddFunc writes to r.Funcs without one
+func newGoEngine(r *Render) Engine {
+	funcs := make(template.FuncMap, len(r.Funcs))
+	for name, fn := range r.Funcs {
+		funcs[name] = fn
+	}
+	can := func(req *http.Request, ability string, args ...interface{}) bool {
+		return r.Can != nil && r.Can(req, ability, args...)
+	}
+	return &GoEngine{Debug: r.Debug, Funcs: funcs, Can: can}
+}
+
+// Load sets the views folder, dropping the cached templates
+func (e *GoEngine) Load(views fs.FS) error {
+	e.mu.Lock()
+	e.views = views
+	e.pages = nil
+	e.mu.Unlock()
+	return nil
+}
+
+// Reload drops the cached templates
+func (e *GoEngine) Reload() error {
+	e.mu.Lock()
+	e.pages = nil
+	e.mu.Unlock()
+	return nil
+}
+
+// AddFunc adds a function for templates parsed from now on
+func (e *GoEngine) AddFunc(name string, fn interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Funcs == nil {
+		e.Funcs = make(template.FuncMap)
+	}
+	e.Funcs[name] = fn
+}
+
+// Render executes the page of v with v.Data. The cached template set is
+// shared by all requests; v.Funcs aren't used, see "can" above.
+func (e *GoEngine) Render(w io.Writer, v *View) error {
+	tmpl, err := e.template(v.Name)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, v.Data)
+}
+
+// template returns the cached template set of a page, parsing it if needed
+func (e *GoEngine) template(view string) (*template.Template, error) {
+	if !e.Debug {
+		e.mu.RLock()
+		tmpl, ok := e.pages[view]
+		e.mu.RUnlock()
+		if ok {
+			return tmpl, nil
+		}
+	}
+
+	tmpl, err := e.parse(view)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.Debug {
+		e.mu.Lock()
+		if e.pages == nil {
+			e.pages = make(map[string]*template.Template)
+		}
+		e.pages[view] = tmpl
+		e.mu.Unlock()
+	}
+	return tmpl, nil
+}
+
+// parse parses a page with the shared files
+func (e *GoEngine) parse(view string) (*template.Template, error) {
+	e.mu.RLock()
+	views := e.views
+	funcs := template.FuncMap{"can": e.can}
+	for name, fn := range e.Funcs {
+		funcs[name] = fn
+	}
+	e.mu.RUnlock()
+
+	if views == nil {
+		return nil, fmt.Errorf("render: the go engine has no views loaded")
+	}
+
+	page := view + ".page.tmpl"
+	shared, err := goSharedFiles(views)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(path.Base(page)).Funcs(funcs).ParseFS(views, append([]string{page}, shared...)...)
+	if err != nil {
+		return nil, fmt.Errorf("parsing view %s: %w", view, err)
+	}
+	return tmpl, nil
+}
+
+// can is the "can" template function, finding the request in the page's data
+func (e *GoEngine) can(td *TemplateData, ability string, args ...interface{}) bool {
+	if e.Can == nil || td == nil || td.request == nil {
+		return false
+	}
+	return e.Can(td.request, ability, args...)
+}
+
+// goSharedFiles returns the layouts and partials in views, in a stable order
+func goSharedFiles(views fs.FS) ([]string, error) {
+	var files []string
+	err := fs.WalkDir(views, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if !d.IsDir() && (strings.HasSuffix(name, ".layout.tmpl") || strings.HasSuffix(name, ".partial.tmpl")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+func TestGoEngineCanUsesTheRenderedRequest(t *testing.T) {
+	views := fstest.MapFS{
+		"home.page.tmpl": {Data: []byte(`{{template "base" .}}{{define "content"}}` +
+			`{{if can . "edit"}}edit{{else}}view{{end}}` +
+			`{{range .Data.posts}}{{if can $ "delete" .}} delete {{.}}{{end}}{{end}}{{end}}`)},
+		"base.layout.tmpl": {Data: []byte(`{{define "base"}}<p>{{block "content" .}}{{end}}</p>{{end}}`)},
+	}
+
+	sm := scs.New()
+	r := &Render{
+		Renderer: "go",
+		Session:  sm,
+		Can: func(req *http.Request, ability string, args ...interface{}) bool {
+			return req.Header.Get("X-Role") == "admin"
+		},
+	}
+	if err := r.Load(views); err != nil {
+		t.Fatal(err)
+	}
+
+	page := sm.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data := &TemplateData{Data: map[string]interface{}{"posts": []string{"a"}}}
+		if err := r.Page(w, req, "home", nil, data); err != nil {
+			t.Error(err)
+		}
+	}))
+
+	// requests rendered at the same time each get their own answers
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		role, want := "guest", "<p>view</p>"
+		if i%2 == 0 {
+			role, want = "admin", "<p>edit delete a</p>"
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Role", role)
+			w := httptest.NewRecorder()
+			page.ServeHTTP(w, req)
+			if got := w.Body.String(); got != want {
+				t.Errorf("%s got %q, want %q", role, got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,152 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/CloudyKit/jet/v6"
+)
+
+// JetEngine renders Jet templates, views/<view>.jet. In Debug, Jet re-reads
+// templates on every render.
+type JetEngine struct {
+	// Set renders the views. If nil, Load creates one reading from the views
+	// folder; set it to configure Jet yourself, e.g. with other delimiters.
+	Set   *jet.Set
+	Debug bool
+
+	globals map[string]interface{} // added before Set existed
+	cache   *jetCache
+}
+
+func newJetEngine(r *Render) Engine {
+	e := &JetEngine{Set: r.JetViews, Debug: r.Debug}
+	for name, fn := range r.Funcs {
+		e.AddFunc(name, fn)
+	}
+	return e
+}
+
+// Load creates the Set reading from views, unless Set was given
+func (e *JetEngine) Load(views fs.FS) error {
+	if e.Set == nil {
+		e.cache = &jetCache{}
+		opts := []jet.Option{jet.WithCache(e.cache)}
+		if e.Debug {
+			opts = append(opts, jet.InDevelopmentMode())
+		}
+		e.Set = jet.NewSet(&jetFSLoader{fsys: views}, opts...)
+	}
+
+	for name, fn := range e.globals {
+		e.Set.AddGlobal(name, fn)
+	}
+	e.globals = nil
+	return nil
+}
+
+// Reload drops the parsed templates. A Set passed in by the app keeps its
+// own cache, which only InDevelopmentMode bypasses.
+func (e *JetEngine) Reload() error {
+	if e.cache != nil {
+		e.cache.clear()
+	}
+	return nil
+}
+
+// AddFunc adds a global function
+func (e *JetEngine) AddFunc(name string, fn interface{}) {
+	if e.Set != nil {
+		e.Set.AddGlobal(name, fn)
+		return
+	}
+	if e.globals == nil {
+		e.globals = make(map[string]interface{})
+	}
+	e.globals[name] = fn
+}
+
+// Render executes the template of v. v.Variables must be a jet.VarMap or nil;
+// the shared values and request functions are added to it unless the handler
+// set variables of the same name.
+func (e *JetEngine) Render(w io.Writer, v *View) error {
+	if e.Set == nil {
+		return fmt.Errorf("render: the jet engine has no views loaded")
+	}
+
+	// the handler's map is copied, so shared values and request funcs added
+	// below don't leak into it, e.g. when it renders several views with it
+	vars := make(jet.VarMap)
+	if v.Variables != nil {
+		vm, ok := v.Variables.(jet.VarMap)
+		if !ok {
+			return fmt.Errorf("render: variables must be a jet.VarMap, not %T", v.Variables)
+		}
+		for name, value := range vm {
+			vars[name] = value
+		}
+	}
+
+	for name, value := range v.Shared {
+		if _, ok := vars[name]; !ok {
+			vars.Set(name, value)
+		}
+	}
+	for name, fn := range v.Funcs {
+		if _, ok := vars[name]; !ok {
+			vars.Set(name, fn)
+		}
+	}
+
+	t, err := e.Set.GetTemplate(v.Name + ".jet")
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, vars, v.Data)
+}
+
+// jetFSLoader loads Jet templates from an fs.FS
+type jetFSLoader struct {
+	fsys fs.FS
+}
+
+func (l *jetFSLoader) name(templatePath string) string {
+	return strings.TrimPrefix(path.Clean("/"+templatePath), "/")
+}
+
+func (l *jetFSLoader) Exists(templatePath string) bool {
+	info, err := fs.Stat(l.fsys, l.name(templatePath))
+	return err == nil && !info.IsDir()
+}
+
+func (l *jetFSLoader) Open(templatePath string) (io.ReadCloser, error) {
+	return l.fsys.Open(l.name(templatePath))
+}
+
+// jetCache is Jet's template cache, with a way to empty it
+type jetCache struct {
+	m sync.Map
+}
+
+func (c *jetCache) Get(templatePath string) *jet.Template {
+	t, ok := c.m.Load(templatePath)
+	if !ok {
+		return nil
+	}
+	return t.(*jet.Template)
+}
+
+func (c *jetCache) Put(templatePath string, t *jet.Template) {
+	c.m.Store(templatePath, t)
+}
+
+func (c *jetCache) clear() {
+	c.m.Range(func(key, _ interface{}) bool {
+		c.m.Delete(key)
+		return true
+	})
+}
@@ -1,10 +1,14 @@
 package render
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"html"
 	"html/template"
-	"log"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/CloudyKit/jet/v6"
@@ -12,14 +16,35 @@ import (
 	"github.com/justinas/nosurf"
 )
 
+var (
+	// ErrNoRenderer is returned when rendering a page without a RENDERER set
+	ErrNoRenderer = errors.New("render: no renderer configured, set RENDERER to \"go\" or \"jet\"")
+	// ErrUnknownRenderer is returned for RENDERER values no engine is registered for
+	ErrUnknownRenderer = errors.New("render: unknown renderer")
+)
+
 type Render struct {
 	Renderer   string // Renderer is the name of the rendering engine that we want to use
 	RootPath   string // path to the folder that holds the views
 	Secure     bool   // true if we want to use HTTPS
 	Port       string
 	ServerName string
-	JetViews   *jet.Set
-	Session    *scs.SessionManager
+	// Engine renders the pages. Load creates it from Renderer unless it's set.
+	Engine Engine
+	// JetViews is the set of the Jet engine, if that's the one in use
+	JetViews *jet.Set
+	Session  *scs.SessionManager
+	// Can backs the "can" template function, e.g. {{ if can("update", post) }} in Jet
+	// or {{ if can . "update" .Data.post }} in Go templates
+	Can func(r *http.Request, ability string, args ...interface{}) bool
+	// Debug makes the engines re-read templates on every render instead of
+	// caching them, and shows render errors in the response
+	Debug bool
+	// Funcs are extra functions for all templates. Set them before Load, or
+	// use AddFunc.
+	Funcs template.FuncMap
+
+	composers []composer
 }
 
 // TemplateData is a struct that holds the data that we want to pass to the templates
@@ -35,6 +60,11 @@ type TemplateData struct {
 	Secure          bool
 	Error           string
 	Flash           string
+	Flashes         []FlashMessage      // flashed messages with their levels, see Render.Flash
+	OldInput        url.Values          // form values submitted before a redirect, see Render.FlashInput
+	FormErrors      map[string][]string // validation errors per field, see Render.FlashErrors
+
+	request *http.Request // the request being rendered, for the Go engine's "can"
 }
 
 func (r *Render) defaultData(td *TemplateData, req *http.Request) *TemplateData {
@@ -48,6 +78,7 @@ func (r *Render) defaultData(td *TemplateData, req *http.Request) *TemplateData
 	}
 
 	td.CSRFToken = nosurf.Token(req) // add the CSRF token to the template data
+	td.request = req
 
 	td.Port = r.Port
 	td.ServerName = r.ServerName
@@ -58,81 +89,123 @@ func (r *Render) defaultData(td *TemplateData, req *http.Request) *TemplateData
 	td.Error = r.Session.PopString(req.Context(), "error")
 	td.Flash = r.Session.PopString(req.Context(), "flash")
 
+	// typed flash messages, old form input and validation errors
+	r.popFlashed(req.Context(), td)
+
 	return td
 }
 
-// Page Function will render a page
-func (r *Render) Page(w http.ResponseWriter, req *http.Request, view string, variables, data interface{}) error {
-	// view is the name of the view (or template) that we want to render
-
-	switch strings.ToLower(r.Renderer) {
-	case "go":
-		// render the page using the Go template engine
-		r.GoPage(w, req, view, data)
-	case "jet":
-		// render the page using the Jet template engine
-		r.JetPage(w, req, view, variables, data)
+// requestFuncs returns the template functions that depend on the request being rendered
+func (r *Render) requestFuncs(req *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"can": func(ability string, args ...interface{}) bool {
+			return r.Can != nil && r.Can(req, ability, args...)
+		},
 	}
-	return nil
 }
 
-// GoPage renders a standard Go template
-func (r *Render) GoPage(w http.ResponseWriter, req *http.Request, view string, data interface{}) error {
-	// render the page using the Go template engine
+// Page renders a view with the configured engine. The page is rendered
+// into a buffer first and only written out when it rendered completely. If
+// rendering fails, Page responds with a 500 itself, showing the error and
+// where in the template it happened when Debug is on, and returns the error
+// so the handler can log it.
+//
+// variables are engine specific, e.g. a jet.VarMap for Jet; data must be a
+// *TemplateData. Either may be nil.
+func (r *Render) Page(w http.ResponseWriter, req *http.Request, view string, variables, data interface{}) error {
+	// view is the name of the view (or template) that we want to render
 
-	tmpl, err := template.ParseFiles(fmt.Sprintf("%s/views/%s.page.tmpl", r.RootPath, view))
+	var buf bytes.Buffer
+	err := r.render(&buf, req, view, variables, data)
 	if err != nil {
+		err = fmt.Errorf("rendering %s: %w", view, err)
+		r.renderError(w, err)
 		return err
 	}
 
-	td := &TemplateData{}
-	if data != nil {
-		templateData, ok := data.(*TemplateData)
-		if !ok {
-			return fmt.Errorf("data is not of type TemplateData")
-		} else {
-			td = templateData
-		}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	}
+	_, err = buf.WriteTo(w)
+	return err
+}
 
-	// add the default data to the template data
-	td = r.defaultData(td, req)
+// render adds the default and composed data and has the engine render the view to w
+func (r *Render) render(w io.Writer, req *http.Request, view string, variables, data interface{}) error {
+	if r.Engine == nil {
+		if r.Renderer == "" {
+			return ErrNoRenderer
+		}
+		return fmt.Errorf("render: the %s engine isn't loaded", r.Renderer)
+	}
 
-	err = tmpl.Execute(w, td)
+	td, err := templateData(data)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// add the default data to the template data
+	td = r.defaultData(td, req)
+	shared := r.compose(req, view, td)
+
+	return r.Engine.Render(w, &View{
+		Name:      view,
+		Request:   req,
+		Data:      td,
+		Variables: variables,
+		Shared:    shared,
+		Funcs:     r.requestFuncs(req),
+	})
 }
 
-// JetPage renders a template using the Jet templating engine
-func (r *Render) JetPage(w http.ResponseWriter, req *http.Request, templateName string, variables, data interface{}) error {
-	var vars jet.VarMap
-
-	if variables == nil {
-		vars = make(jet.VarMap)
-	} else {
-		vars = variables.(jet.VarMap)
+// Validate checks that an engine is registered for Renderer, so a
+// misconfigured RENDERER fails at startup instead of on the first request
+func (r *Render) Validate() error {
+	if r.Renderer == "" || r.Engine != nil {
+		return nil
 	}
+	if _, ok := engineFactory(r.Renderer); !ok {
+		return fmt.Errorf("%w: %q, use one of %s", ErrUnknownRenderer, r.Renderer, strings.Join(Engines(), ", "))
+	}
+	return nil
+}
 
-	td := &TemplateData{}
-	if data != nil {
-		td = data.(*TemplateData)
+// renderError responds to a failed render, with the details only in Debug
+func (r *Render) renderError(w http.ResponseWriter, err error) {
+	if !r.Debug {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
 
-	// add the default data to the template data
-	td = r.defaultData(td, req)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, debugErrorPage, html.EscapeString(err.Error()))
+}
 
-	t, err := r.JetViews.GetTemplate(fmt.Sprintf("%s.jet", templateName))
-	if err != nil {
-		log.Println(err)
-		return err
+// debugErrorPage shows a render error. The Go and Jet engines both name the
+// template file and line in their errors.
+const debugErrorPage = `<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>Template error</title></head>
+<body style="font-family: sans-serif; margin: 2em">
+<h1>Template error</h1>
+<pre style="background: #fdecea; padding: 1em; white-space: pre-wrap">%s</pre>
+</body>
+</html>
+`
+
+// templateData checks the data passed to a render
+func templateData(data interface{}) (*TemplateData, error) {
+	if data == nil {
+		return &TemplateData{}, nil
 	}
 
-	if err = t.Execute(w, vars, td); err != nil {
-		log.Println(err)
-		return err
+	td, ok := data.(*TemplateData)
+	if !ok {
+		return nil, fmt.Errorf("render: data must be a *render.TemplateData, not %T", data)
 	}
-	return nil
+	if td == nil {
+		return &TemplateData{}, nil
+	}
+	return td, nil
 }
@@ -0,0 +1,294 @@
+package goravel
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/saalikmubeen/goravel/auth"
+	"github.com/saalikmubeen/goravel/jwt"
+)
+
+// responseCachePrefix is prepended to the cache key of every cached response,
+// so that all of them can be flushed at once with EmptyByMatch
+const responseCachePrefix = "response:"
+
+// cachedResponse is what gets stored in the cache for a GET request
+type cachedResponse struct {
+	Status       int
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified time.Time
+}
+
+func init() {
+	// the cache backends gob encode values stored behind an interface{},
+	// which only works for registered types
+	gob.Register(cachedResponse{})
+}
+
+// CacheResponse returns a middleware that stores full GET responses (status,
+// headers and body) in g.Cache for ttl. The cache key is built from the path,
+// the query string and the values of the given request headers, e.g.
+//
+//	r.With(app.App.CacheResponse(10*time.Minute, "Accept-Language")).Get("/", app.Handlers.Home)
+//
+// Cached responses carry an ETag and Last-Modified header, and conditional
+// requests that match them get a 304. ttl is rounded up to whole seconds.
+//
+// Only anonymous requests are cached. Requests bypass the cache when
+//   - the session holds a logged in user, before or after the handler ran
+//   - the session holds flashed messages, old input or form errors for the
+//     next page, which a shared copy would leave out
+//   - the handler changed the session, e.g. by starting one
+//   - they carry an Authorization header, e.g. an API token or a JWT
+//   - the request context holds a user or JWT claims
+//
+// Routes outside SessionLoad can be cached too; they just have no session to check.
+//
+// Other cookies are not part of the cache key: list "Cookie" in varyHeaders
+// if a page depends on them. Responses that set a cookie are never stored.
+// Rendered pages embed the visitor's CSRF token, so don't put this in front
+// of routes that render forms.
+func (g *Goravel) CacheResponse(ttl time.Duration, varyHeaders ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if g.Cache == nil || !g.cacheableRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := responseCacheKey(r, varyHeaders)
+
+			if fromCache, err := g.Cache.Get(key); err == nil {
+				if cached, ok := fromCache.(cachedResponse); ok {
+					w.Header().Set("X-Cache", "HIT")
+					writeCachedResponse(w, r, &cached)
+					return
+				}
+			}
+
+			rec := &responseRecorder{
+				header: http.Header{},
+				status: http.StatusOK,
+			}
+			next.ServeHTTP(rec, r)
+
+			// e.g. a remember me cookie logged the visitor in on the way, or
+			// the handler put something in a session the visitor now gets a cookie for
+			personal := !g.cacheableRequest(r) ||
+				(g.hasSession(r) && g.Session.Status(r.Context()) != scs.Unmodified)
+
+			cached := &cachedResponse{
+				Status:       rec.status,
+				Header:       rec.header,
+				Body:         rec.body.Bytes(),
+				LastModified: time.Now().UTC().Truncate(time.Second),
+			}
+
+			if personal || !cacheableResponse(cached) {
+				for k, v := range rec.header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(rec.status)
+				_, _ = w.Write(cached.Body)
+				return
+			}
+
+			sum := sha256.Sum256(cached.Body)
+			cached.ETag = fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16]))
+
+			// a HEAD response has no body, so only GETs populate the cache
+			if r.Method == http.MethodGet {
+				err := g.Cache.Set(key, *cached, cacheSeconds(ttl))
+				if err != nil {
+					g.ErrorLog.Println("could not cache response:", err)
+				}
+			}
+
+			w.Header().Set("X-Cache", "MISS")
+			writeCachedResponse(w, r, cached)
+		})
+	}
+}
+
+// FlushResponseCache removes every response stored by CacheResponse
+func (g *Goravel) FlushResponseCache() error {
+	if g.Cache == nil {
+		return nil
+	}
+	return g.Cache.EmptyByMatch(responseCachePrefix)
+}
+
+// cacheableRequest reports whether a request may be served from (and stored in) the cache
+func (g *Goravel) cacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if strings.Contains(r.Header.Get("Cache-Control"), "no-store") {
+		return false
+	}
+
+	// authenticated users get pages rendered for them, never a shared copy,
+	// as do visitors with flashed data waiting to be shown
+	if g.hasSession(r) {
+		if g.Session.Exists(r.Context(), "userID") {
+			return false
+		}
+		if g.Render != nil && g.Render.HasFlashed(r.Context()) {
+			return false
+		}
+	}
+
+	// API tokens and JWTs authenticate per request, without a session
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+	if _, ok := auth.UserFromContext(r.Context()); ok {
+		return false
+	}
+	if _, ok := jwt.ClaimsFromContext(r.Context()); ok {
+		return false
+	}
+
+	return true
+}
+
+// hasSession reports whether r went through SessionLoad. scs panics when
+// asked about a request it didn't load a session for, and routes can be
+// cached outside the web middleware.
+func (g *Goravel) hasSession(r *http.Request) (loaded bool) {
+	if g.Session == nil {
+		return false
+	}
+
+	defer func() {
+		if recover() != nil {
+			loaded = false
+		}
+	}()
+	g.Session.Status(r.Context())
+	return true
+}
+
+// cacheSeconds rounds ttl up to whole seconds, at least one, as the backends
+// disagree on what an expiry of 0 means
+func cacheSeconds(ttl time.Duration) int {
+	s := int((ttl + time.Second - 1) / time.Second)
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+// cacheableResponse reports whether a response produced by a handler may be stored
+func cacheableResponse(c *cachedResponse) bool {
+	if c.Status != http.StatusOK {
+		return false
+	}
+
+	cacheControl := c.Header.Get("Cache-Control")
+	if strings.Contains(cacheControl, "no-store") || strings.Contains(cacheControl, "private") {
+		return false
+	}
+
+	// a handler setting its own cookie is personalising the response
+	return c.Header.Get("Set-Cookie") == ""
+}
+
+// responseCacheKey builds the cache key from the path, the sorted query string
+// and the values of the headers the response varies on
+func responseCacheKey(r *http.Request, varyHeaders []string) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteString("?")
+	b.WriteString(r.URL.Query().Encode()) // Encode sorts by key
+
+	headers := append([]string{}, varyHeaders...)
+	sort.Strings(headers)
+	for _, h := range headers {
+		b.WriteString("\n")
+		b.WriteString(http.CanonicalHeaderKey(h))
+		b.WriteString(":")
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return responseCachePrefix + hex.EncodeToString(sum[:])
+}
+
+// writeCachedResponse writes a stored response, or a 304 if the client's copy is still fresh
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, c *cachedResponse) {
+	for k, v := range c.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("ETag", c.ETag)
+	w.Header().Set("Last-Modified", c.LastModified.Format(http.TimeFormat))
+
+	if notModified(r, c) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(c.Status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(c.Body)
+	}
+}
+
+// notModified checks the conditional request headers against a cached response.
+// If-None-Match takes precedence over If-Modified-Since, as per RFC 7232.
+func notModified(r *http.Request, c *cachedResponse) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, etag := range strings.Split(inm, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == c.ETag || etag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		if err == nil && !c.LastModified.After(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// responseRecorder buffers a handler's response so it can be stored before it is sent
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *responseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = status
+	rec.wroteHeader = true
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	return rec.body.Write(b)
+}
@@ -0,0 +1,133 @@
+package goravel
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/saalikmubeen/goravel/cache"
+	"github.com/saalikmubeen/goravel/render"
+)
+
+func newResponseCacheApp(t *testing.T) *Goravel {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLoggingLevel(badger.ERROR))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	sm := scs.New()
+	return &Goravel{
+		Cache:    &cache.BadgerCache{Conn: db},
+		Session:  sm,
+		Render:   &render.Render{Session: sm},
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+}
+
+// serve sends a GET through handler and returns the X-Cache header and the body
+func serve(t *testing.T, handler http.Handler, cookies ...*http.Cookie) (string, string, []*http.Cookie) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/page", nil)
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Header().Get("X-Cache"), w.Body.String(), w.Result().Cookies()
+}
+
+func TestCacheResponseSkipsFlashedSessions(t *testing.T) {
+	g := newResponseCacheApp(t)
+
+	page := g.CacheResponse(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page " + g.Session.PopString(r.Context(), "flash")))
+	}))
+	handler := g.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("flash") != "" {
+			g.Session.Put(r.Context(), "flash", "Saved")
+			return
+		}
+		page.ServeHTTP(w, r)
+	}))
+
+	// a visitor with a flash message waiting gets their own page
+	r := httptest.NewRequest("GET", "/page?flash=1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	session := w.Result().Cookies()
+
+	if x, body, _ := serve(t, handler, session...); x != "" || body != "page Saved" {
+		t.Fatalf("page with a pending flash = %q %q", x, body)
+	}
+
+	// and the next anonymous visitor doesn't see it in a cached copy
+	if x, body, _ := serve(t, handler); x != "MISS" || body != "page " {
+		t.Fatalf("first anonymous page = %q %q", x, body)
+	}
+	if x, body, _ := serve(t, handler); x != "HIT" || body != "page " {
+		t.Fatalf("second anonymous page = %q %q", x, body)
+	}
+}
+
+func TestCacheResponseSkipsHandlersStartingASession(t *testing.T) {
+	g := newResponseCacheApp(t)
+
+	handler := g.Session.LoadAndSave(g.CacheResponse(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Session.Put(r.Context(), "visited", true)
+		w.Write([]byte("page"))
+	})))
+
+	for i := 0; i < 2; i++ {
+		x, _, cookies := serve(t, handler)
+		if x == "HIT" || x == "MISS" {
+			t.Fatalf("a page that started a session was cached (X-Cache %s)", x)
+		}
+		if len(cookies) == 0 {
+			t.Fatal("the session cookie was lost")
+		}
+	}
+}
+
+func TestCacheResponseWithoutSessionLoad(t *testing.T) {
+	g := newResponseCacheApp(t)
+
+	handler := g.CacheResponse(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	}))
+
+	if x, _, _ := serve(t, handler); x != "MISS" {
+		t.Fatalf("first page X-Cache = %q, want MISS", x)
+	}
+	if x, _, _ := serve(t, handler); x != "HIT" {
+		t.Fatalf("second page X-Cache = %q, want HIT", x)
+	}
+}
+
+func TestCacheResponseSkipsOldInput(t *testing.T) {
+	g := newResponseCacheApp(t)
+
+	handler := g.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("input") != "" {
+			g.Render.FlashInput(r.Context(), url.Values{"email": {"user@example.com"}})
+			return
+		}
+		g.CacheResponse(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("form"))
+		})).ServeHTTP(w, r)
+	}))
+
+	r := httptest.NewRequest("GET", "/page?input=1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if x, _, _ := serve(t, handler, w.Result().Cookies()...); x != "" {
+		t.Fatalf("form with old input waiting was served with X-Cache %q", x)
+	}
+}
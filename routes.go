@@ -21,6 +21,12 @@ func (g *Goravel) initRoutes() http.Handler {
 	// load the session
 	mux.Use(g.SessionLoad)
 
+	// look the logged in user up at most once per request
+	mux.Use(g.Auth.CacheUser)
+
+	// keep track of logged in users' sessions so they can be listed and revoked
+	mux.Use(g.TrackSessions)
+
 	// add the CSRF protection
 	mux.Use(g.NoSurf)
 
@@ -28,6 +28,11 @@ func (g *Goravel) ListenAndServe() error {
 		defer redisPool.Close() // close the redis connection when the server stops
 	}
 
+	// registered after the pool, so it runs first: the invalidator still needs the pool to unsubscribe
+	if myNearCache != nil {
+		defer myNearCache.Close() // stop listening for cache invalidations
+	}
+
 	if badgerConn != nil {
 		defer badgerConn.Close()
 	}
@@ -0,0 +1,10 @@
+package goravel
+
+import "net/http"
+
+// RenewToken gives the current session a new token while keeping its data.
+// Call it whenever the user's privilege level changes, e.g. on login, so that
+// a token planted by an attacker before login (session fixation) is worthless.
+func (g *Goravel) RenewToken(r *http.Request) error {
+	return g.Session.RenewToken(r.Context())
+}
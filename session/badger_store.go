@@ -0,0 +1,95 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// badgerPrefix keeps session keys apart from anything else stored in the same database
+const badgerPrefix = "scs:session:"
+
+// BadgerStore is an scs session store backed by an embedded badger database.
+// Expired sessions are removed by badger's own TTL handling. When the database
+// is shared with the badger cache, pruning the cache logs everybody out.
+type BadgerStore struct {
+	db *badger.DB
+}
+
+// NewBadgerStore returns a session store that keeps sessions in db
+func NewBadgerStore(db *badger.DB) *BadgerStore {
+	return &BadgerStore{db: db}
+}
+
+// Find returns the data for a session token
+func (s *BadgerStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(badgerPrefix + token))
+		if err != nil {
+			return err
+		}
+
+		b, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+// Commit stores the session data until expiry
+func (s *BadgerStore) Commit(token string, b []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return s.Delete(token)
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(badgerPrefix+token), b).WithTTL(ttl))
+	})
+}
+
+// Delete removes a session token and its data
+func (s *BadgerStore) Delete(token string) error {
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(badgerPrefix + token))
+	})
+}
+
+// All returns the data of every active session, keyed by token
+func (s *BadgerStore) All() (map[string][]byte, error) {
+	sessions := make(map[string][]byte)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(badgerPrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if item.IsDeletedOrExpired() {
+				continue
+			}
+
+			b, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			sessions[string(item.Key()[len(prefix):])] = b
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/saalikmubeen/goravel/cache"
+)
+
+// cachePrefix keeps session keys apart from the application's own cache keys
+const cachePrefix = "scs:session:"
+
+// CacheStore is an scs session store that keeps sessions in any cache.Cache
+// implementation. Note that pruning the cache logs everybody out.
+type CacheStore struct {
+	cache cache.Cache
+}
+
+// NewCacheStore returns a session store that keeps sessions in c
+func NewCacheStore(c cache.Cache) *CacheStore {
+	return &CacheStore{cache: c}
+}
+
+// Find returns the data for a session token
+func (s *CacheStore) Find(token string) ([]byte, bool, error) {
+	// the cache backends report a missing key as an error from Get, but leave
+	// it out of GetMany's result, which tells "not found" apart from a real
+	// failure in a single lookup
+	key := cachePrefix + token
+	values, err := s.cache.GetMany(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, found := values[key]
+	if !found {
+		return nil, false, nil
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("session %s holds %T, not session data", token, value)
+	}
+
+	return b, true, nil
+}
+
+// Commit stores the session data until expiry
+func (s *CacheStore) Commit(token string, b []byte, expiry time.Time) error {
+	// the cache only deals in whole seconds, so round up rather than expire early
+	seconds := int(math.Ceil(time.Until(expiry).Seconds()))
+	if seconds <= 0 {
+		return s.Delete(token)
+	}
+
+	return s.cache.Set(cachePrefix+token, b, seconds)
+}
+
+// Delete removes a session token and its data
+func (s *CacheStore) Delete(token string) error {
+	return s.cache.Delete(cachePrefix + token)
+}
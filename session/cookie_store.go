@@ -0,0 +1,251 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// MaxCookieSize is the largest encoded session a CookieStore will write.
+// Browsers reject cookies over 4096 bytes, and the name and attributes need some room too.
+const MaxCookieSize = 3800
+
+// ErrCookieTooLarge is returned when the encrypted session doesn't fit in a cookie
+var ErrCookieTooLarge = errors.New("session data is too large to be stored in a cookie")
+
+// errNoCommitTarget is returned when a CookieStore commit happens outside LoadAndSave
+var errNoCommitTarget = errors.New("cookie store sessions must be committed through CookieStore.LoadAndSave")
+
+// cookieFormatVersion is the first byte of every encrypted cookie, so the format can change later
+const cookieFormatVersion byte = 1
+
+type commitTargetKey struct{}
+
+// CookieStore is a stateless scs session store. Instead of a random token
+// pointing at server side data, the cookie itself holds the session data,
+// encrypted and authenticated with AES-GCM. Nothing is stored on the server,
+// so sessions survive restarts and work across any number of instances. For
+// the same reason a session can't be revoked before it expires, and a
+// replayed older cookie brings back the older session data.
+//
+// scs writes whatever the store's token is into the cookie, so the store has
+// to hand the freshly encrypted data back to the middleware; use
+// CookieStore.LoadAndSave instead of SessionManager.LoadAndSave.
+type CookieStore struct {
+	aeads []cipher.AEAD // the first one encrypts, all of them are tried to decrypt
+}
+
+// NewCookieStore returns a cookie store. The first key encrypts new cookies;
+// any further keys are only used to decrypt, which allows rotating keys
+// without logging everybody out. Keys of any length are stretched to AES-256 keys.
+func NewCookieStore(keys ...string) (*CookieStore, error) {
+	if len(keys) == 0 || keys[0] == "" {
+		return nil, errors.New("cookie store needs at least one encryption key")
+	}
+
+	c := &CookieStore{}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		c.aeads = append(c.aeads, aead)
+	}
+
+	return c, nil
+}
+
+// Find decrypts the session data held in the cookie. Tampered, undecryptable
+// or expired cookies are reported as not found.
+func (c *CookieStore) Find(token string) ([]byte, bool, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < 1 || raw[0] != cookieFormatVersion {
+		return nil, false, nil
+	}
+	raw = raw[1:]
+
+	for _, aead := range c.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+
+		nonce, ciphertext := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, []byte{cookieFormatVersion})
+		if err != nil || len(plaintext) < 8 {
+			continue
+		}
+
+		expiry := time.Unix(0, int64(binary.BigEndian.Uint64(plaintext[:8])))
+		if time.Now().After(expiry) {
+			return nil, false, nil
+		}
+
+		return plaintext[8:], true, nil
+	}
+
+	return nil, false, nil
+}
+
+// Commit is not supported without a request context; see CommitCtx
+func (c *CookieStore) Commit(token string, b []byte, expiry time.Time) error {
+	return errNoCommitTarget
+}
+
+// Delete is a no-op: the cookie is removed from the browser by the session manager
+func (c *CookieStore) Delete(token string) error {
+	return nil
+}
+
+// FindCtx is the same as Find
+func (c *CookieStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	return c.Find(token)
+}
+
+// CommitCtx encrypts the session data and hands it to the LoadAndSave
+// middleware, which writes it into the cookie in place of the token
+func (c *CookieStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	target, ok := ctx.Value(commitTargetKey{}).(*string)
+	if !ok {
+		return errNoCommitTarget
+	}
+
+	encoded, err := c.encrypt(b, expiry)
+	if err != nil {
+		return err
+	}
+
+	*target = encoded
+	return nil
+}
+
+// DeleteCtx is the same as Delete
+func (c *CookieStore) DeleteCtx(ctx context.Context, token string) error {
+	return c.Delete(token)
+}
+
+// encrypt seals the expiry and session data with the current key
+func (c *CookieStore) encrypt(b []byte, expiry time.Time) (string, error) {
+	aead := c.aeads[0]
+
+	plaintext := make([]byte, 8+len(b))
+	binary.BigEndian.PutUint64(plaintext[:8], uint64(expiry.UnixNano()))
+	copy(plaintext[8:], b)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	raw := append([]byte{cookieFormatVersion}, nonce...)
+	raw = aead.Seal(raw, nonce, plaintext, []byte{cookieFormatVersion})
+
+	encoded := base64.RawURLEncoding.EncodeToString(raw)
+	if len(encoded) > MaxCookieSize {
+		return "", ErrCookieTooLarge
+	}
+
+	return encoded, nil
+}
+
+// LoadAndSave does the job of SessionManager.LoadAndSave for a cookie store:
+// it loads the session from the cookie, and writes the re-encrypted session
+// back into the cookie before the response is written
+func (c *CookieStore) LoadAndSave(sm *scs.SessionManager, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Cookie")
+
+		var token string
+		cookie, err := r.Cookie(sm.Cookie.Name)
+		if err == nil {
+			token = cookie.Value
+		}
+
+		ctx, err := sm.Load(r.Context(), token)
+		if err != nil {
+			sm.ErrorFunc(w, r, err)
+			return
+		}
+
+		sr := r.WithContext(ctx)
+		cw := &cookieResponseWriter{
+			ResponseWriter: w,
+			request:        sr,
+			sessionManager: sm,
+		}
+
+		next.ServeHTTP(cw, sr)
+
+		if !cw.written {
+			commitAndWriteCookie(sm, w, sr)
+		}
+	})
+}
+
+// commitAndWriteCookie encrypts a modified session into the response's cookie
+func commitAndWriteCookie(sm *scs.SessionManager, w http.ResponseWriter, r *http.Request) {
+	switch sm.Status(r.Context()) {
+	case scs.Modified:
+		var encoded string
+		ctx := context.WithValue(r.Context(), commitTargetKey{}, &encoded)
+
+		_, expiry, err := sm.Commit(ctx)
+		if err != nil {
+			sm.ErrorFunc(w, r, err)
+			return
+		}
+
+		sm.WriteSessionCookie(ctx, w, encoded, expiry)
+	case scs.Destroyed:
+		sm.WriteSessionCookie(r.Context(), w, "", time.Time{})
+	}
+}
+
+// cookieResponseWriter writes the session cookie just before the headers go out
+type cookieResponseWriter struct {
+	http.ResponseWriter
+	request        *http.Request
+	sessionManager *scs.SessionManager
+	written        bool
+}
+
+func (cw *cookieResponseWriter) Write(b []byte) (int, error) {
+	if !cw.written {
+		commitAndWriteCookie(cw.sessionManager, cw.ResponseWriter, cw.request)
+		cw.written = true
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *cookieResponseWriter) WriteHeader(code int) {
+	if !cw.written {
+		commitAndWriteCookie(cw.sessionManager, cw.ResponseWriter, cw.request)
+		cw.written = true
+	}
+
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *cookieResponseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,146 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+func newTestCookieStore(t *testing.T, keys ...string) *CookieStore {
+	t.Helper()
+	c, err := NewCookieStore(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCookieStoreRoundTrip(t *testing.T) {
+	c := newTestCookieStore(t, "current key")
+
+	token, err := c.encrypt([]byte("session data"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(token, "session data") {
+		t.Fatal("the cookie holds the session data in clear text")
+	}
+
+	b, found, err := c.Find(token)
+	if err != nil || !found || string(b) != "session data" {
+		t.Fatalf("Find = %q, %v, %v", b, found, err)
+	}
+}
+
+func TestCookieStoreRejectsBadCookies(t *testing.T) {
+	c := newTestCookieStore(t, "current key")
+
+	token, err := c.encrypt([]byte(`{"userID":1}`), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := c.encrypt([]byte(`{"userID":1}`), time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newTestCookieStore(t, "someone else's key").encrypt([]byte(`{"userID":1}`), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flip a bit in the middle of the ciphertext
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[len(raw)/2] ^= 1
+
+	for name, token := range map[string]string{
+		"tampered":    base64.RawURLEncoding.EncodeToString(raw),
+		"truncated":   token[:len(token)-4],
+		"expired":     expired,
+		"foreign key": other,
+		"not base64":  "not a cookie!",
+		"empty":       "",
+	} {
+		if b, found, err := c.Find(token); found || err != nil {
+			t.Errorf("Find(%s cookie) = %q, %v, %v", name, b, found, err)
+		}
+	}
+}
+
+func TestCookieStoreKeyRotation(t *testing.T) {
+	old := newTestCookieStore(t, "old key")
+	token, err := old.encrypt([]byte("session data"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestCookieStore(t, "new key", "old key")
+	if b, found, _ := rotated.Find(token); !found || string(b) != "session data" {
+		t.Fatal("a cookie of the old key can't be read after rotating")
+	}
+
+	fresh, err := rotated.encrypt([]byte("session data"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := old.Find(fresh); found {
+		t.Fatal("new cookies are still encrypted with the old key")
+	}
+}
+
+func TestCookieStoreTooLarge(t *testing.T) {
+	c := newTestCookieStore(t, "current key")
+	if _, err := c.encrypt(make([]byte, MaxCookieSize), time.Now().Add(time.Hour)); !errors.Is(err, ErrCookieTooLarge) {
+		t.Fatalf("encrypt(oversized session) = %v, want ErrCookieTooLarge", err)
+	}
+}
+
+func TestCookieStoreLoadAndSave(t *testing.T) {
+	sm := scs.New()
+	c := newTestCookieStore(t, "current key")
+	sm.Store = c
+
+	handler := c.LoadAndSave(sm, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/put" {
+			sm.Put(r.Context(), "userID", 7)
+		}
+		w.Write([]byte(sm.GetString(r.Context(), "greeting") + strings.Repeat("!", sm.GetInt(r.Context(), "userID"))))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/put", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value == "" {
+		t.Fatalf("no session cookie written, got %v", cookies)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got := w.Body.String(); got != "!!!!!!!" {
+		t.Fatalf("the session wasn't restored from the cookie, body %q", got)
+	}
+}
+
+func TestInitSessionCookieNeedsKey(t *testing.T) {
+	if _, err := (&Session{SessionType: "cookie"}).InitSession(); err == nil || !strings.Contains(err.Error(), "requires KEY") {
+		t.Fatalf("InitSession without a key = %v, want an error asking for KEY", err)
+	}
+
+	sm, err := (&Session{SessionType: "Cookie", CookieKeys: []string{"current key"}}).InitSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sm.Store.(*CookieStore); !ok {
+		t.Fatalf("store is %T, want *CookieStore", sm.Store)
+	}
+}
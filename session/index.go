@@ -0,0 +1,185 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// IndexRecord describes one session belonging to a user
+type IndexRecord struct {
+	Token     string    `json:"token"`
+	UserID    int       `json:"user_id"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Index maps a user to the tokens of their active sessions, so that the
+// sessions can be listed and revoked. The session data itself stays in the
+// scs store; the index only holds metadata.
+type Index interface {
+	// Save inserts or updates a record. CreatedAt is kept from the first save.
+	Save(rec IndexRecord) error
+	// All returns every record saved for a user
+	All(userID int) ([]IndexRecord, error)
+	// Remove drops the given tokens from a user's index
+	Remove(userID int, tokens ...string) error
+}
+
+// RedisIndex keeps the index in one redis hash per user, keyed by token
+type RedisIndex struct {
+	Pool     *redis.Pool
+	Lifetime time.Duration // the hash expires this long after the user's last activity
+	Prefix   string        // key prefix, "scs:index:" if empty
+}
+
+func (i *RedisIndex) key(userID int) string {
+	prefix := i.Prefix
+	if prefix == "" {
+		prefix = "scs:index:"
+	}
+	return prefix + strconv.Itoa(userID)
+}
+
+// Save inserts or updates a record
+func (i *RedisIndex) Save(rec IndexRecord) error {
+	conn := i.Pool.Get()
+	defer conn.Close()
+
+	existing, err := redis.Bytes(conn.Do("HGET", i.key(rec.UserID), rec.Token))
+	if err != nil && err != redis.ErrNil {
+		return err
+	}
+	if err == nil {
+		var old IndexRecord
+		if json.Unmarshal(existing, &old) == nil && !old.CreatedAt.IsZero() {
+			rec.CreatedAt = old.CreatedAt
+		}
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	_ = conn.Send("HSET", i.key(rec.UserID), rec.Token, b)
+	if i.Lifetime > 0 {
+		_ = conn.Send("EXPIRE", i.key(rec.UserID), int(i.Lifetime.Seconds()))
+	}
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+// All returns every record saved for a user
+func (i *RedisIndex) All(userID int) ([]IndexRecord, error) {
+	conn := i.Pool.Get()
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("HVALS", i.key(userID)))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]IndexRecord, 0, len(values))
+	for _, v := range values {
+		var rec IndexRecord
+		if err := json.Unmarshal(v, &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// Remove drops the given tokens from a user's index
+func (i *RedisIndex) Remove(userID int, tokens ...string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	conn := i.Pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{}.Add(i.key(userID)).AddFlat(tokens)
+	_, err := conn.Do("HDEL", args...)
+	return err
+}
+
+// SQLIndex keeps the index in the session_index table created by "goravel make session"
+type SQLIndex struct {
+	DB           *sql.DB
+	DatabaseType string // "postgres" or "mysql"
+}
+
+func (i *SQLIndex) postgres() bool {
+	return i.DatabaseType == "postgres" || i.DatabaseType == "postgresql" || i.DatabaseType == "pgx"
+}
+
+// placeholder returns the n-th (1 based) bind parameter for the database in use
+func (i *SQLIndex) placeholder(n int) string {
+	if i.postgres() {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// Save inserts or updates a record
+func (i *SQLIndex) Save(rec IndexRecord) error {
+	upsert := "ON DUPLICATE KEY UPDATE user_agent = VALUES(user_agent), ip_address = VALUES(ip_address), last_seen = VALUES(last_seen)"
+	if i.postgres() {
+		upsert = "ON CONFLICT (token) DO UPDATE SET user_agent = EXCLUDED.user_agent, ip_address = EXCLUDED.ip_address, last_seen = EXCLUDED.last_seen"
+	}
+
+	query := fmt.Sprintf(`INSERT INTO session_index (token, user_id, user_agent, ip_address, created_at, last_seen)
+		VALUES (%s, %s, %s, %s, %s, %s) %s`,
+		i.placeholder(1), i.placeholder(2), i.placeholder(3), i.placeholder(4), i.placeholder(5), i.placeholder(6), upsert)
+
+	_, err := i.DB.Exec(query, rec.Token, rec.UserID, rec.UserAgent, rec.IP, rec.CreatedAt.UTC(), rec.LastSeen.UTC())
+	return err
+}
+
+// All returns every record saved for a user
+func (i *SQLIndex) All(userID int) ([]IndexRecord, error) {
+	query := fmt.Sprintf(`SELECT token, user_id, user_agent, ip_address, created_at, last_seen
+		FROM session_index WHERE user_id = %s ORDER BY last_seen DESC`, i.placeholder(1))
+
+	rows, err := i.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []IndexRecord
+	for rows.Next() {
+		var rec IndexRecord
+		err := rows.Scan(&rec.Token, &rec.UserID, &rec.UserAgent, &rec.IP, &rec.CreatedAt, &rec.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	return records, rows.Err()
+}
+
+// Remove drops the given tokens from a user's index
+func (i *SQLIndex) Remove(userID int, tokens ...string) error {
+	query := fmt.Sprintf("DELETE FROM session_index WHERE user_id = %s AND token = %s", i.placeholder(1), i.placeholder(2))
+
+	for _, token := range tokens {
+		if _, err := i.DB.Exec(query, userID, token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -11,21 +12,36 @@ import (
 	"github.com/alexedwards/scs/postgresstore"
 	"github.com/alexedwards/scs/redisstore"
 	"github.com/alexedwards/scs/v2"
+	"github.com/dgraph-io/badger/v3"
 	"github.com/gomodule/redigo/redis"
+	"github.com/saalikmubeen/goravel/cache"
 )
 
 type Session struct {
-	CookieLifetime string
-	CookiePersist  string
-	CookieName     string
-	CookieDomain   string
-	CookieSecure   string
-	SessionType    string
-	DBPool         *sql.DB
-	RedisPool      *redis.Pool
+	CookieLifetime    string // minutes
+	CookieIdleTimeout string // minutes of inactivity after which the session expires, 0 to disable
+	CookiePersist     string
+	CookieName        string
+	CookieDomain      string
+	CookieSecure      string
+	CookiePath        string
+	CookieSameSite    string // "lax", "strict" or "none"
+	CookieHTTPOnly    string
+	CookieHostPrefix  string // "true" to name the cookie __Host-<name>, which locks it to this exact host
+	SessionType       string
+	DBPool            *sql.DB
+	RedisPool         *redis.Pool
+	RedisPrefix       string      // key prefix of the redis store, "scs:session:" if empty
+	BadgerConn        *badger.DB  // used when SessionType is "badger"
+	Cache             cache.Cache // used when SessionType is "cache"
+	// CookieKeys encrypt the cookie store. The first key encrypts,
+	// older keys are kept only to decrypt existing cookies after a rotation.
+	CookieKeys []string
 }
 
-func (s *Session) InitSession() *scs.SessionManager {
+// InitSession creates the session manager with the configured store. It fails
+// when SESSION_TYPE is cookie but there is no key to encrypt the cookies.
+func (s *Session) InitSession() (*scs.SessionManager, error) {
 	var persist, secure bool
 
 	// how long should sessions last?
@@ -48,27 +64,83 @@ func (s *Session) InitSession() *scs.SessionManager {
 		secure = false
 	}
 
+	// how long may a session sit unused?
+	idleMinutes, err := strconv.Atoi(s.CookieIdleTimeout)
+	if err != nil {
+		idleMinutes = 0
+	}
+
+	// cookies are kept away from javascript unless explicitly turned off
+	httpOnly := strings.ToLower(s.CookieHTTPOnly) != "false"
+
+	path := s.CookiePath
+	if path == "" {
+		path = "/"
+	}
+
 	// create session
 	session := scs.New()
 	session.Lifetime = time.Duration(minutes) * time.Minute
+	session.IdleTimeout = time.Duration(idleMinutes) * time.Minute
 	session.Cookie.Persist = persist
 	session.Cookie.Name = s.CookieName
 	session.Cookie.Secure = secure
 	session.Cookie.Domain = s.CookieDomain
-	session.Cookie.SameSite = http.SameSiteLaxMode
+	session.Cookie.Path = path
+	session.Cookie.HttpOnly = httpOnly
+	session.Cookie.SameSite = sameSiteMode(s.CookieSameSite)
+
+	// browsers only accept a __Host- cookie that is secure, has no
+	// domain and is sent for the whole site
+	if strings.ToLower(s.CookieHostPrefix) == "true" {
+		session.Cookie.Name = "__Host-" + strings.TrimPrefix(s.CookieName, "__Host-")
+		session.Cookie.Secure = true
+		session.Cookie.Domain = ""
+		session.Cookie.Path = "/"
+	}
 
 	// which session store?
 	switch strings.ToLower(s.SessionType) {
 	case "redis":
-		session.Store = redisstore.New(s.RedisPool)
+		if s.RedisPrefix != "" {
+			session.Store = redisstore.NewWithPrefix(s.RedisPool, s.RedisPrefix)
+		} else {
+			session.Store = redisstore.New(s.RedisPool)
+		}
 
 	case "mysql", "mariadb":
 		session.Store = mysqlstore.New(s.DBPool)
 	case "postgres", "postgresql":
 		session.Store = postgresstore.New(s.DBPool)
-	default: // "cookie"
-		// by default, use the cookie store
+	case "badger":
+		session.Store = NewBadgerStore(s.BadgerConn)
+	case "cache":
+		session.Store = NewCacheStore(s.Cache)
+	case "cookie":
+		store, err := NewCookieStore(s.CookieKeys...)
+		if err != nil {
+			return nil, fmt.Errorf("SESSION_TYPE=cookie requires KEY: %w", err)
+		}
+		session.Store = store
+	default:
+		// without a SESSION_TYPE, use the cookie store if there is a key
+		// to encrypt the cookies, and scs's in-memory store otherwise
+		if store, err := NewCookieStore(s.CookieKeys...); err == nil {
+			session.Store = store
+		}
 	}
 
-	return session
+	return session, nil
+}
+
+// sameSiteMode converts the SameSite setting to its http constant, defaulting to lax
+func sameSiteMode(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
 }
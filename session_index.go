@@ -0,0 +1,213 @@
+package goravel
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/saalikmubeen/goravel/session"
+)
+
+// ErrSessionIndexUnsupported is returned by the session management functions
+// when SESSION_TYPE isn't one of the stores that can be indexed (redis, mysql or postgres)
+var ErrSessionIndexUnsupported = errors.New("listing and revoking sessions needs SESSION_TYPE redis, mysql or postgres")
+
+// ErrSessionNotFound is returned when revoking a session the user doesn't have
+var ErrSessionNotFound = errors.New("session not found")
+
+// sessionIndexSeenKey holds when the current session was last written to the index,
+// in Unix seconds as the session codec can't gob encode a time.Time, and
+// sessionIndexTokenKey the token it was written under
+const (
+	sessionIndexSeenKey  = "__sessionIndexSeen"
+	sessionIndexTokenKey = "__sessionIndexToken"
+)
+
+// sessionIndexInterval is how often an active session's last seen time is refreshed
+const sessionIndexInterval = time.Minute
+
+// SessionInfo describes one of a user's active sessions, e.g. for a "your devices" page.
+// The session token itself is never exposed; ID is derived from it instead.
+type SessionInfo struct {
+	ID        string
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	Current   bool // true for the session making the request
+}
+
+// createSessionIndex picks the index matching the session store, or nil if the store can't be indexed
+func (g *Goravel) createSessionIndex() session.Index {
+	switch g.config.sessionType {
+	case "redis":
+		index := &session.RedisIndex{Pool: redisPool, Lifetime: g.Session.Lifetime}
+		if g.config.redis.mode == "cluster" {
+			index.Prefix = g.config.redis.keyPrefix() + ":index:"
+		}
+		return index
+	case "mysql", "mariadb", "postgres", "postgresql":
+		return &session.SQLIndex{DB: g.DB.Pool, DatabaseType: g.DB.DatabaseType}
+	}
+	return nil
+}
+
+// TrackSessions is a middleware that records the sessions of logged in users,
+// along with their user agent, IP address and last activity. It is added to
+// the router by default and does nothing for stores that can't be indexed.
+func (g *Goravel) TrackSessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if g.sessionIndex == nil || !g.Session.Exists(ctx, "userID") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// a brand new session has no token until it is committed; it gets
+		// picked up on the next request
+		token := g.Session.Token(ctx)
+		lastSeen := time.Unix(g.Session.GetInt64(ctx, sessionIndexSeenKey), 0)
+		// a renewed token (e.g. on login) is indexed straight away
+		renewed := g.Session.GetString(ctx, sessionIndexTokenKey) != token
+
+		if token != "" && (renewed || time.Since(lastSeen) > sessionIndexInterval) {
+			now := time.Now()
+			err := g.sessionIndex.Save(session.IndexRecord{
+				Token:     token,
+				UserID:    g.Session.GetInt(ctx, "userID"),
+				UserAgent: r.UserAgent(),
+				IP:        r.RemoteAddr,
+				CreatedAt: now,
+				LastSeen:  now,
+			})
+			if err != nil {
+				g.ErrorLog.Println("could not index session:", err)
+			} else {
+				g.Session.Put(ctx, sessionIndexSeenKey, now.Unix())
+				g.Session.Put(ctx, sessionIndexTokenKey, token)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ListUserSessions returns the active sessions of a user, most recently used
+// first. ctx is the current request's context, used to flag the current session.
+func (g *Goravel) ListUserSessions(ctx context.Context, userID int) ([]SessionInfo, error) {
+	records, err := g.liveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := g.Session.Token(ctx)
+
+	sessions := make([]SessionInfo, 0, len(records))
+	for _, rec := range records {
+		sessions = append(sessions, SessionInfo{
+			ID:        sessionID(rec.Token),
+			UserAgent: rec.UserAgent,
+			IP:        rec.IP,
+			CreatedAt: rec.CreatedAt,
+			LastSeen:  rec.LastSeen,
+			Current:   rec.Token == current,
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession logs a user out of the session with the given ID (as returned by ListUserSessions)
+func (g *Goravel) RevokeSession(userID int, id string) error {
+	records, err := g.liveSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		if sessionID(rec.Token) == id {
+			return g.revokeTokens(userID, rec.Token)
+		}
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeAllExcept logs a user out of every session but the one in ctx,
+// e.g. for a "log out other devices" button or after a password change
+func (g *Goravel) RevokeAllExcept(ctx context.Context, userID int) error {
+	records, err := g.liveSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	current := g.Session.Token(ctx)
+
+	var tokens []string
+	for _, rec := range records {
+		if rec.Token != current {
+			tokens = append(tokens, rec.Token)
+		}
+	}
+
+	return g.revokeTokens(userID, tokens...)
+}
+
+// liveSessions returns a user's index records, dropping any whose session
+// has since expired, been destroyed or had its token renewed
+func (g *Goravel) liveSessions(userID int) ([]session.IndexRecord, error) {
+	if g.sessionIndex == nil {
+		return nil, ErrSessionIndexUnsupported
+	}
+
+	records, err := g.sessionIndex.All(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var live []session.IndexRecord
+	var stale []string
+	for _, rec := range records {
+		_, found, err := g.Session.Store.Find(rec.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			live = append(live, rec)
+		} else {
+			stale = append(stale, rec.Token)
+		}
+	}
+
+	if err := g.sessionIndex.Remove(userID, stale...); err != nil {
+		return nil, err
+	}
+
+	return live, nil
+}
+
+// revokeTokens deletes sessions from the store and the index
+func (g *Goravel) revokeTokens(userID int, tokens ...string) error {
+	for _, token := range tokens {
+		if err := g.Session.Store.Delete(token); err != nil {
+			return err
+		}
+	}
+	return g.sessionIndex.Remove(userID, tokens...)
+}
+
+// sessionID derives the public ID of a session from its token
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
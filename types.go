@@ -1,6 +1,9 @@
 package goravel
 
-import "database/sql"
+import (
+	"database/sql"
+	"time"
+)
 
 type InitPaths struct {
 	RootPath    string   // rootPath is the path that we are in when we start the goravel app
@@ -15,11 +18,16 @@ type Server struct {
 }
 
 type cookieConfig struct {
-	name     string
-	lifetime string
-	persist  string
-	secure   string
-	domain   string
+	name        string
+	lifetime    string
+	idleTimeout string
+	persist     string
+	secure      string
+	domain      string
+	path        string
+	sameSite    string
+	httpOnly    string
+	hostPrefix  string
 }
 
 type databaseConfig struct {
@@ -33,7 +41,56 @@ type Database struct {
 }
 
 type redisConfig struct {
+	mode     string // "standalone" (default), "sentinel" or "cluster"
 	host     string
+	username string
 	password string
 	prefix   string
+	database int
+
+	// pool tuning
+	maxIdle         int
+	maxActive       int
+	idleTimeout     time.Duration
+	maxConnLifetime time.Duration
+	connectTimeout  time.Duration
+	readTimeout     time.Duration
+	writeTimeout    time.Duration
+	wait            bool // block in Get() when the pool is at maxActive instead of failing
+
+	sentinel redisSentinelConfig
+	cluster  redisClusterConfig
+	tls      redisTLSConfig
+}
+
+type redisClusterConfig struct {
+	addrs []string // host:port of nodes to ask for the cluster layout
+}
+
+type redisSentinelConfig struct {
+	addrs      []string // host:port of each sentinel
+	masterName string
+	password   string
+}
+
+type redisTLSConfig struct {
+	enabled    bool
+	skipVerify bool
+	serverName string
+	caCert     string // path to a PEM encoded CA bundle
+	cert       string // path to a PEM encoded client certificate
+	key        string // path to the client certificate's private key
+}
+
+type badgerConfig struct {
+	path              string // directory holding the database, relative paths are resolved against RootPath
+	inMemory          bool
+	valueLogFileSize  int64 // bytes
+	valueLogMaxEntry  uint32
+	memTableSize      int64  // bytes
+	gcSchedule        string // cron spec for value log garbage collection
+	gcRatio           float64
+	logLevel          string // "debug", "info", "warning", "error" or "none"
+	encrypt           bool   // encrypt the database at rest with the application's EncryptionKey
+	keyRotationPeriod time.Duration
 }
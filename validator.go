@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/saalikmubeen/goravel/auth"
 )
 
 type ErrorsMap map[string][]string
@@ -235,3 +236,27 @@ func (v *Validation) NoWhitespace(field string, value ...string) bool {
 
 	return true
 }
+
+// NotPwned checks that a password isn't on a list of breached passwords.
+// It passes when there is no list, or the list can't be read, so a missing
+// file doesn't stop everybody from signing up.
+func (v *Validation) NotPwned(field string, list *auth.PwnedPasswords, value ...string) bool {
+	validationValue := ""
+
+	if len(value) > 0 {
+		validationValue = value[0]
+	} else {
+		validationValue = v.Data.Get(field)
+	}
+
+	if list == nil {
+		return true
+	}
+
+	pwned, err := list.IsPwned(validationValue)
+	if err == nil && pwned {
+		v.Errors.Add(field, "This password has appeared in a data breach. Please choose another one")
+		return false
+	}
+	return true
+}